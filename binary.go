@@ -0,0 +1,181 @@
+package gammu
+
+/*
+#include <gammu.h>
+*/
+import "C"
+import (
+	"context"
+	"github.com/ziutek/gogammu/pdu"
+	"strings"
+	"sync"
+)
+
+// SendDataSMS sends data as 8-bit message(s). If dstPort >= 0 application
+// port addressing (16-bit) is added to the UDH of every part. Long data is
+// sent as concatenated message.
+func (sm *StateMachine) SendDataSMS(number string, dstPort, srcPort int, data []byte, report bool) ([]SendResult, error) {
+	return sm.SendDataSMSContext(
+		context.Background(), number, dstPort, srcPort, data,
+		&SendOptions{Report: report},
+	)
+}
+
+// SendDataSMSContext works like SendDataSMS but uses opts and stops sending
+// when ctx is done.
+func (sm *StateMachine) SendDataSMSContext(ctx context.Context, number string, dstPort, srcPort int, data []byte, opts *SendOptions) ([]SendResult, error) {
+	if opts == nil {
+		opts = new(SendOptions)
+	}
+	var tmpl pdu.Submit
+	if dstPort >= 0 {
+		tmpl.UDH = pdu.UDH{pdu.PortsIE(dstPort, srcPort)}
+	}
+	sm.concatRef++
+	msgs, err := pdu.SplitData(tmpl, data, sm.concatRef)
+	if err != nil {
+		return nil, EncodeError{C.ERR_MOREMEMORY}
+	}
+	res := make([]SendResult, 0, len(msgs))
+	for i, m := range msgs {
+		var sms C.GSM_SMSMessage
+		setUDH(&sms.UDH, m.UDH)
+		sms.Coding = C.SMS_Coding_8bit
+		sms.Class = C.schar(opts.Class.gammu(-1))
+		for k, b := range m.Data {
+			sms.Text[k] = C.uchar(b)
+		}
+		sms.Length = C.int(len(m.Data))
+		r, err := sm.sendSMS(ctx, &sms, number, opts)
+		if err != nil {
+			return res, err
+		}
+		r.Part = i
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// setUDH sets h as user defined UDH.
+func setUDH(g *C.GSM_UDHHeader, h pdu.UDH) {
+	if len(h) == 0 {
+		g.Type = C.UDH_NoUDH
+		return
+	}
+	b := h.Encode()
+	g.Type = C.UDH_UserUDH
+	g.Length = C.size_t(len(b))
+	for i, c := range b {
+		g.Text[i] = C.uchar(c)
+	}
+}
+
+// getUDH returns UDH decoded from raw header.
+func getUDH(g *C.GSM_UDHHeader) pdu.UDH {
+	if g.Type == C.UDH_NoUDH || g.Length < 1 {
+		return nil
+	}
+	b := make([]byte, int(g.Length))
+	for i := range b {
+		b[i] = byte(g.Text[i])
+	}
+	h, _, err := pdu.DecodeUDH(b)
+	if err != nil {
+		return nil
+	}
+	return h
+}
+
+// udhPorts returns application ports from UDH (-1 if there is no port
+// addressing IE).
+func udhPorts(g *C.GSM_UDHHeader) (dst, src int) {
+	dst, src, ok := getUDH(g).Ports()
+	if !ok {
+		return -1, -1
+	}
+	return
+}
+
+var (
+	escOnce sync.Once
+	escRune rune
+)
+
+// gammuESC returns character that libGammu encodes as escape septet (0x1B)
+// if it isn't followed by character from default extension table, or 0 if
+// there is no such character.
+func gammuESC() rune {
+	escOnce.Do(func() {
+		var u [4]C.uchar
+		esc := [1]C.uchar{0x1b}
+		C.DecodeDefault(&u[0], &esc[0], 1, C.FALSE, nil)
+		r := rune(u[0])<<8 | rune(u[1])
+		// Check that it's encoded back to escape septet
+		var in [6]C.uchar
+		in[0], in[1] = u[0], u[1]
+		in[3] = 'a'
+		var out [4]C.uchar
+		n := C.size_t(2)
+		C.EncodeDefault(&out[0], &in[0], &n, C.TRUE, nil)
+		if n == 2 && out[0] == 0x1b && out[1] == 'a' {
+			escRune = r
+		}
+	})
+	return escRune
+}
+
+// CanSendSingleShift reports whether libGammu can send characters from
+// national language single shift tables.
+func CanSendSingleShift() bool {
+	return gammuESC() != 0
+}
+
+// gammuText returns text that libGammu encodes to septets s. It allows to
+// send septets from national language tables, which libGammu doesn't know.
+func gammuText(s []byte) string {
+	esc := gammuESC()
+	if esc == 0 {
+		return pdu.DecodeGSM7(s)
+	}
+	var b strings.Builder
+	for _, c := range s {
+		if c == 0x1b {
+			b.WriteRune(esc)
+		} else {
+			b.WriteString(pdu.DecodeGSM7([]byte{c}))
+		}
+	}
+	return b.String()
+}
+
+// gammuSeptets reverses decoding of septets by libGammu (see gammuText).
+func gammuSeptets(text string) ([]byte, error) {
+	esc := gammuESC()
+	var s []byte
+	for _, r := range text {
+		if r == esc {
+			s = append(s, 0x1b)
+			continue
+		}
+		b, err := pdu.EncodeGSM7(string(r))
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, b...)
+	}
+	return s, nil
+}
+
+// nationalText decodes text using national language tables selected by UDH.
+// libGammu always decodes text using default alphabet.
+func nationalText(g *C.GSM_UDHHeader, text string) string {
+	h := getUDH(g)
+	if ls, ss := h.Shift(); ls == pdu.LangDefault && ss == pdu.LangDefault {
+		return text
+	}
+	s, err := gammuSeptets(text)
+	if err != nil {
+		return text
+	}
+	return pdu.DecodeSeptets(h, s)
+}
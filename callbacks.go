@@ -0,0 +1,88 @@
+package gammu
+
+/*
+#include <gammu.h>
+*/
+import "C"
+import (
+	"sync"
+	"unsafe"
+)
+
+// Go functions exported to libGammu as callbacks. cgo doesn't allow C
+// definitions in this file, so callbacks are registered in files that use
+// them.
+
+// handlers contains Go functions called by libGammu callbacks. They are
+// stored by C state machine pointer, not in StateMachine, so the finalizer
+// of StateMachine still works.
+type handlers struct {
+	call      func(Call)
+	sms       chan<- IncomingSMS
+	ussd      func(USSD)
+	ussdReply chan USSD // Waiting DialService
+}
+
+var (
+	handlersMu  sync.Mutex
+	handlersMap = make(map[*C.GSM_StateMachine]*handlers)
+)
+
+// setHandlers calls f with handlers of g (created if necessary).
+func setHandlers(g *C.GSM_StateMachine, f func(h *handlers)) {
+	handlersMu.Lock()
+	h := handlersMap[g]
+	if h == nil {
+		h = new(handlers)
+		handlersMap[g] = h
+	}
+	f(h)
+	handlersMu.Unlock()
+}
+
+func getHandlers(g *C.GSM_StateMachine) handlers {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	if h := handlersMap[g]; h != nil {
+		return *h
+	}
+	return handlers{}
+}
+
+func delHandlers(g *C.GSM_StateMachine) {
+	handlersMu.Lock()
+	delete(handlersMap, g)
+	handlersMu.Unlock()
+}
+
+//export goIncomingCall
+func goIncomingCall(g *C.GSM_StateMachine, call *C.GSM_Call, data unsafe.Pointer) {
+	if f := getHandlers(g).call; f != nil {
+		f(decodeCall(call))
+	}
+}
+
+//export goIncomingSMS
+func goIncomingSMS(g *C.GSM_StateMachine, sms *C.GSM_SMSMessage, data unsafe.Pointer) {
+	if c := getHandlers(g).sms; c != nil {
+		select {
+		case c <- decodeIncoming(sms):
+		default:
+		}
+	}
+}
+
+//export goIncomingUSSD
+func goIncomingUSSD(g *C.GSM_StateMachine, ussd *C.GSM_USSDMessage, data unsafe.Pointer) {
+	u := USSD{USSDStatus(ussd.Status), encodeUTF8(&ussd.Text[0])}
+	h := getHandlers(g)
+	if h.ussdReply != nil {
+		select {
+		case h.ussdReply <- u:
+		default:
+		}
+	}
+	if h.ussd != nil {
+		h.ussd(u)
+	}
+}
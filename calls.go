@@ -0,0 +1,140 @@
+package gammu
+
+/*
+#include <stdlib.h>
+#include <gammu.h>
+
+void goIncomingCall(GSM_StateMachine *sm, GSM_Call *call, void *data);
+
+static void setIncomingCallCallback(GSM_StateMachine *sm) {
+	GSM_SetIncomingCallCallback(sm, goIncomingCall, NULL);
+}
+*/
+import "C"
+import "unsafe"
+
+// CallStatus describes call event
+type CallStatus int
+
+const (
+	CallIncoming    CallStatus = C.GSM_CALL_IncomingCall
+	CallOutgoing    CallStatus = C.GSM_CALL_OutgoingCall
+	CallStart       CallStatus = C.GSM_CALL_CallStart
+	CallEnd         CallStatus = C.GSM_CALL_CallEnd
+	CallRemoteEnd   CallStatus = C.GSM_CALL_CallRemoteEnd // Hung up by remote party
+	CallLocalEnd    CallStatus = C.GSM_CALL_CallLocalEnd  // Hung up locally
+	CallEstablished CallStatus = C.GSM_CALL_CallEstablished
+	CallHeld        CallStatus = C.GSM_CALL_CallHeld
+	CallResumed     CallStatus = C.GSM_CALL_CallResumed
+	CallSwitched    CallStatus = C.GSM_CALL_CallSwitched
+)
+
+func (s CallStatus) String() string {
+	switch s {
+	case CallIncoming:
+		return "incoming"
+	case CallOutgoing:
+		return "outgoing"
+	case CallStart:
+		return "start"
+	case CallEnd:
+		return "end"
+	case CallRemoteEnd:
+		return "remote end"
+	case CallLocalEnd:
+		return "local end"
+	case CallEstablished:
+		return "established"
+	case CallHeld:
+		return "held"
+	case CallResumed:
+		return "resumed"
+	case CallSwitched:
+		return "switched"
+	}
+	return "unknown"
+}
+
+// Ended returns true if s means that call has ended.
+func (s CallStatus) Ended() bool {
+	return s == CallEnd || s == CallRemoteEnd || s == CallLocalEnd
+}
+
+// Call describes call event reported by phone
+type Call struct {
+	Status     CallStatus
+	ID         int    // Call ID (-1 if not available)
+	Number     string // Caller ID (empty if not available)
+	StatusCode int    // Phone specific status code
+}
+
+func decodeCall(c *C.GSM_Call) Call {
+	call := Call{
+		Status:     CallStatus(c.Status),
+		ID:         -1,
+		Number:     encodeUTF8(&c.PhoneNumber[0]),
+		StatusCode: int(c.StatusCode),
+	}
+	if c.CallIDAvailable != 0 {
+		call.ID = int(c.CallID)
+	}
+	return call
+}
+
+// Dial makes voice call to number.
+func (sm *StateMachine) Dial(number string) error {
+	cn := C.CString(number)
+	defer C.free(unsafe.Pointer(cn))
+	if e := C.GSM_DialVoice(sm.g, cn, C.GSM_CALL_DefaultNumberPresence); e != C.ERR_NONE {
+		return Error{"DialVoice", e}
+	}
+	return nil
+}
+
+// AnswerCall answers incoming call with specified id or all calls if id < 0.
+func (sm *StateMachine) AnswerCall(id int) error {
+	all := C.gboolean(C.FALSE)
+	if id < 0 {
+		id, all = 0, C.TRUE
+	}
+	if e := C.GSM_AnswerCall(sm.g, C.int(id), all); e != C.ERR_NONE {
+		return Error{"AnswerCall", e}
+	}
+	return nil
+}
+
+// CancelCall hangs up (or rejects) call with specified id or all calls if
+// id < 0.
+func (sm *StateMachine) CancelCall(id int) error {
+	all := C.gboolean(C.FALSE)
+	if id < 0 {
+		id, all = 0, C.TRUE
+	}
+	if e := C.GSM_CancelCall(sm.g, C.int(id), all); e != C.ERR_NONE {
+		return Error{"CancelCall", e}
+	}
+	return nil
+}
+
+// SetIncomingCall enables reporting of call events to f (or disables it if
+// f == nil). It should be called after every Connect. f is called from
+// inside of StateMachine methods (eg. ReadDevice), so it can't call them.
+func (sm *StateMachine) SetIncomingCall(f func(Call)) error {
+	setHandlers(sm.g, func(h *handlers) { h.call = f })
+	enable := C.gboolean(C.FALSE)
+	if f != nil {
+		C.setIncomingCallCallback(sm.g)
+		enable = C.TRUE
+	}
+	if e := C.GSM_SetIncomingCall(sm.g, enable); e != C.ERR_NONE {
+		return Error{"SetIncomingCall", e}
+	}
+	return nil
+}
+
+// ReadDevice reads and processes data received from phone, without waiting
+// for it. Use it to receive events (eg. incoming calls) when StateMachine
+// isn't used for other things. It returns number of bytes read.
+func (sm *StateMachine) ReadDevice() int {
+	return int(C.GSM_ReadDevice(sm.g, C.FALSE))
+}
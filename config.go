@@ -0,0 +1,139 @@
+package gammu
+
+/*
+#include <stdlib.h>
+#include <string.h>
+#include <gammu.h>
+
+// Device, Connection and DebugFile are freed by GSM_FreeStateMachine.
+// libGammu uses DebugFile and DebugLevel only if UseGlobalDebugFile is false.
+static void setConfig(GSM_Config *c, char *device, char *conn, char *logFile,
+	char *model, char *logFormat, gboolean sync, gboolean lock) {
+	c->Device = device;
+	c->Connection = conn;
+	c->DebugFile = logFile;
+	strncpy(c->Model, model, sizeof(c->Model) - 1);
+	strncpy(c->DebugLevel, logFormat, sizeof(c->DebugLevel) - 1);
+	c->SyncTime = sync;
+	c->LockDevice = lock;
+	c->StartInfo = FALSE;
+	c->UseGlobalDebugFile = (logFile[0] == '\0');
+	strcpy(c->TextReminder, "Reminder");
+	strcpy(c->TextMeeting, "Meeting");
+	strcpy(c->TextCall, "Call");
+	strcpy(c->TextBirthday, "Birthday");
+	strcpy(c->TextMemo, "Memo");
+}
+*/
+import "C"
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// Config describes connection to the phone, like section of gammurc file.
+type Config struct {
+	Device     string // eg. "/dev/ttyUSB0"
+	Connection string // eg. "at", "at115200" (default "at")
+	Model      string // Empty for autodetection
+	SyncTime   bool   // Set phone time on connect
+	LockDevice bool   // Create lock file for device
+	LogFile    string // libGammu debug log (empty for none)
+	LogFormat  string // eg. "text", "textall" (default "text" if LogFile set)
+}
+
+func newStateMachine() *StateMachine {
+	sm := new(StateMachine)
+	sm.g = C.GSM_AllocStateMachine()
+	if sm.g == nil {
+		panic("out of memory")
+	}
+	return sm
+}
+
+func (sm *StateMachine) init() {
+	C.GSM_SetConfigNum(sm.g, 1)
+	sm.Timeout = 15 * time.Second
+	runtime.SetFinalizer(sm, (*StateMachine).free)
+}
+
+// NewStateMachineFromConfig creates new state machine using cfg instead of
+// configuration file.
+func NewStateMachineFromConfig(cfg Config) (*StateMachine, error) {
+	if cfg.Device == "" {
+		return nil, Error{"ReadConfig", C.ERR_UNCONFIGURED}
+	}
+	if cfg.Connection == "" {
+		cfg.Connection = "at"
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "nothing"
+		if cfg.LogFile != "" {
+			cfg.LogFormat = "text"
+		}
+	}
+	sync, lock := C.gboolean(C.FALSE), C.gboolean(C.FALSE)
+	if cfg.SyncTime {
+		sync = C.TRUE
+	}
+	if cfg.LockDevice {
+		lock = C.TRUE
+	}
+	// GSM_SetDebugFile doesn't accept NULL
+	logFile := C.CString(cfg.LogFile)
+	model := C.CString(cfg.Model)
+	defer C.free(unsafe.Pointer(model))
+	format := C.CString(cfg.LogFormat)
+	defer C.free(unsafe.Pointer(format))
+
+	sm := newStateMachine()
+	C.setConfig(
+		C.GSM_GetConfig(sm.g, 0), C.CString(cfg.Device),
+		C.CString(cfg.Connection), logFile, model, format, sync, lock,
+	)
+	sm.init()
+	return sm, nil
+}
+
+// sectionNum returns number of gammurc section specified by name ("gammu",
+// "gammu1", ...) or by number ("", "0", "1", ...).
+func sectionNum(section string) (int, bool) {
+	s := strings.TrimPrefix(section, "gammu")
+	if s == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n >= 0
+}
+
+// NewStateMachineSection works like NewStateMachine but reads specified
+// section of configuration file, by name ("gammu", "gammu1", ...) or by number
+// ("0", "1", ...).
+func NewStateMachineSection(cf, section string) (*StateMachine, error) {
+	num, ok := sectionNum(section)
+	if !ok {
+		return nil, Error{"ReadConfig", C.ERR_NONE_SECTION}
+	}
+	var config *C.INI_Section
+	var cs *C.char
+	if cf != "" {
+		cs = C.CString(cf)
+		defer C.free(unsafe.Pointer(cs))
+	}
+	if e := C.GSM_FindGammuRC(&config, cs); e != C.ERR_NONE {
+		return nil, Error{"FindGammuRC", e}
+	}
+	defer C.INI_Free(config)
+
+	sm := newStateMachine()
+	e := C.GSM_ReadConfig(config, C.GSM_GetConfig(sm.g, 0), C.int(num))
+	if e != C.ERR_NONE {
+		sm.free()
+		return nil, Error{"ReadConfig", e}
+	}
+	sm.init()
+	return sm, nil
+}
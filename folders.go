@@ -0,0 +1,132 @@
+package gammu
+
+/*
+#include <gammu.h>
+*/
+import "C"
+
+// MemoryType specifies phone memory
+type MemoryType int
+
+const (
+	MemPhone      MemoryType = C.MEM_ME // Internal phone memory
+	MemSIM        MemoryType = C.MEM_SM // SIM card memory
+	MemOwnNumbers MemoryType = C.MEM_ON // Own numbers
+	MemDialled    MemoryType = C.MEM_DC // Dialled calls
+	MemReceived   MemoryType = C.MEM_RC // Received calls
+	MemMissed     MemoryType = C.MEM_MC // Missed calls
+	MemCombined   MemoryType = C.MEM_MT // Combined phone and SIM memory
+	MemFixed      MemoryType = C.MEM_FD // Fixed dialling numbers
+	MemVoiceMail  MemoryType = C.MEM_VM // Voice mailbox
+)
+
+var memNames = map[MemoryType]string{
+	MemPhone:      "ME",
+	MemSIM:        "SM",
+	MemOwnNumbers: "ON",
+	MemDialled:    "DC",
+	MemReceived:   "RC",
+	MemMissed:     "MC",
+	MemCombined:   "MT",
+	MemFixed:      "FD",
+	MemVoiceMail:  "VM",
+}
+
+// String returns memory name as used by AT commands (ME, SM, ...)
+func (m MemoryType) String() string {
+	if s, ok := memNames[m]; ok {
+		return s
+	}
+	return "??"
+}
+
+// SMSFolder describes one SMS folder in phone
+type SMSFolder struct {
+	Number int // Folder number (use it for ListSMS)
+	Name   string
+	Inbox  bool // Folder contains received messages
+	Outbox bool // Folder contains messages to send or sent
+	Memory MemoryType
+}
+
+// GetSMSFolders returns list of SMS folders available in phone.
+func (sm *StateMachine) GetSMSFolders() ([]SMSFolder, error) {
+	var folders C.GSM_SMSFolders
+	if e := C.GSM_GetSMSFolders(sm.g, &folders); e != C.ERR_NONE {
+		return nil, Error{"GetSMSFolders", e}
+	}
+	ret := make([]SMSFolder, int(folders.Number))
+	for i := range ret {
+		f := &folders.Folder[i]
+		ret[i] = SMSFolder{
+			Number: i + 1,
+			Name:   encodeUTF8(&f.Name[0]),
+			Inbox:  f.InboxFolder != 0,
+			Outbox: f.OutboxFolder != 0,
+			Memory: MemoryType(f.Memory),
+		}
+	}
+	return ret, nil
+}
+
+// ListSMSMemory returns iterator over messages stored in all folders that
+// belong to specified memory (eg. MemSIM or MemPhone).
+func (sm *StateMachine) ListSMSMemory(mem MemoryType) *SMSList {
+	return &SMSList{sm: sm, memory: mem, start: true}
+}
+
+// SMSMemoryStatus describes usage of SMS memories
+type SMSMemoryStatus struct {
+	SIMUnread, SIMUsed, SIMSize       int
+	PhoneUnread, PhoneUsed, PhoneSize int
+	TemplatesUsed                     int
+}
+
+// GetSMSStatus returns number of used and all locations in SIM and phone
+// memory.
+func (sm *StateMachine) GetSMSStatus() (status SMSMemoryStatus, err error) {
+	var s C.GSM_SMSMemoryStatus
+	if e := C.GSM_GetSMSStatus(sm.g, &s); e != C.ERR_NONE {
+		err = Error{"GetSMSStatus", e}
+		return
+	}
+	status.SIMUnread = int(s.SIMUnRead)
+	status.SIMUsed = int(s.SIMUsed)
+	status.SIMSize = int(s.SIMSize)
+	status.PhoneUnread = int(s.PhoneUnRead)
+	status.PhoneUsed = int(s.PhoneUsed)
+	status.PhoneSize = int(s.PhoneSize)
+	status.TemplatesUsed = int(s.TemplatesUsed)
+	return
+}
+
+// MoveSMS moves message from one folder to another (eg. from SIM inbox to
+// phone inbox). location and folder specify message like for DeleteSMS.
+// It returns location of message in destination folder.
+func (sm *StateMachine) MoveSMS(location, folder, dstFolder int) (int, error) {
+	var msms C.GSM_MultiSMSMessage
+	msms.SMS[0].Location = C.int(location)
+	msms.SMS[0].Folder = C.int(folder)
+	if e := C.GSM_GetSMS(sm.g, &msms); e != C.ERR_NONE {
+		return 0, Error{"GetSMS", e}
+	}
+	newLocation := 0
+	for i := 0; i < int(msms.Number); i++ {
+		s := msms.SMS[i]
+		s.Folder = C.int(dstFolder)
+		s.Location = 0
+		if e := C.GSM_AddSMS(sm.g, &s); e != C.ERR_NONE {
+			return 0, Error{"AddSMS", e}
+		}
+		if i == 0 {
+			newLocation = int(s.Location)
+		}
+	}
+	for i := 0; i < int(msms.Number); i++ {
+		s := msms.SMS[i]
+		if e := C.GSM_DeleteSMS(sm.g, &s); e != C.ERR_NONE {
+			return newLocation, Error{"DeleteSMS", e}
+		}
+	}
+	return newLocation, nil
+}
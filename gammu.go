@@ -5,14 +5,20 @@ package gammu
 #include <stdlib.h>
 #include <gammu.h>
 
+typedef struct {
+	GSM_Error err;
+	int msgRef;
+} sendStatus;
 void sendCallback(GSM_StateMachine *sm, int status, int msgRef, void *data) {
+	sendStatus *s = (sendStatus *) data;
 	if (status==0) {
-		*((GSM_Error *) data) = ERR_NONE;
+		s->err = ERR_NONE;
 	} else {
-		*((GSM_Error *) data) = ERR_UNKNOWN;
+		s->err = ERR_UNKNOWN;
 	}
+	s->msgRef = msgRef;
 }
-void setStatusCallback(GSM_StateMachine *sm, GSM_Error *status) {
+void setStatusCallback(GSM_StateMachine *sm, sendStatus *status) {
 	GSM_SetSendSMSStatusCallback(sm, sendCallback, status);
 }
 GSM_Debug_Info *debug_info;
@@ -26,9 +32,10 @@ void setDebug() {
 */
 import "C"
 import (
+	"context"
 	"fmt"
+	"github.com/ziutek/gogammu/pdu"
 	"io"
-	"runtime"
 	"time"
 	"unsafe"
 )
@@ -59,61 +66,61 @@ func (e EncodeError) Error() string {
 type StateMachine struct {
 	g      *C.GSM_StateMachine
 	smsc   C.GSM_SMSC
-	status C.GSM_Error
+	status C.sendStatus
+
+	concatRef byte // Reference number for concatenated messages
 
 	Timeout time.Duration // Default 15s
+
+	// Unlock is called by Connect after connection is initialized but
+	// before phone is used, so it can enter PIN (see EnterSecurityCode).
+	// Connect fails with returned error.
+	Unlock func(sm *StateMachine) error
 }
 
 // Creates new state maschine using cf configuration file or default
 // configuration file if cf == "".
 func NewStateMachine(cf string) (*StateMachine, error) {
 	//C.setDebug()
-	var config *C.INI_Section
-	if cf != "" {
-		cs := C.CString(cf)
-		defer C.free(unsafe.Pointer(cs))
-		if e := C.GSM_FindGammuRC(&config, cs); e != C.ERR_NONE {
-			return nil, Error{"FindGammuRC", e}
-		}
-	} else {
-		if e := C.GSM_FindGammuRC(&config, nil); e != C.ERR_NONE {
-			return nil, Error{"FindGammuRC", e}
-		}
-	}
-	defer C.INI_Free(config)
-
-	sm := new(StateMachine)
-	sm.g = C.GSM_AllocStateMachine()
-	if sm.g == nil {
-		panic("out of memory")
-	}
-
-	if e := C.GSM_ReadConfig(config, C.GSM_GetConfig(sm.g, 0), 0); e != C.ERR_NONE {
-		sm.free()
-		return nil, Error{"ReadConfig", e}
-	}
-	C.GSM_SetConfigNum(sm.g, 1)
-	sm.Timeout = 15 * time.Second
-
-	runtime.SetFinalizer(sm, (*StateMachine).free)
-	return sm, nil
+	return NewStateMachineSection(cf, "")
 }
 
 func (sm *StateMachine) free() {
 	if sm.IsConnected() {
 		sm.Disconnect()
 	}
+	delHandlers(sm.g)
 	C.GSM_FreeStateMachine(sm.g)
 	sm.g = nil
 }
 
 func (sm *StateMachine) Connect() error {
+	return sm.ConnectContext(context.Background())
+}
+
+// ConnectContext works like Connect but gives up if ctx is done. libGammu
+// calls can't be interrupted so ctx is checked between them.
+func (sm *StateMachine) ConnectContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if e := C.GSM_InitConnection(sm.g, 1); e != C.ERR_NONE {
 		return Error{"InitConnection", e}
 	}
 	C.setStatusCallback(sm.g, &sm.status)
+	if err := ctx.Err(); err != nil {
+		sm.Disconnect()
+		return err
+	}
+	if sm.Unlock != nil {
+		if err := sm.Unlock(sm); err != nil {
+			sm.Disconnect()
+			return err
+		}
+	}
 	sm.smsc.Location = 1
 	if e := C.GSM_GetSMSC(sm.g, &sm.smsc); e != C.ERR_NONE {
+		sm.Disconnect()
 		return Error{"GetSMSC", e}
 	}
 	return nil
@@ -150,59 +157,184 @@ func decodeUTF8(out *C.uchar, in string) {
 	C.free(unsafe.Pointer(cn))
 }
 
-func (sm *StateMachine) sendSMS(sms *C.GSM_SMSMessage, number string, report bool) error {
-	C.CopyUnicodeString(&sms.SMSC.Number[0], &sm.smsc.Number[0])
+// MaxParts is maximum number of parts of long message
+const MaxParts = C.GSM_MAX_MULTI_SMS
+
+// SendResult describes one sent message (one part of a long message).
+type SendResult struct {
+	MsgRef int       // TP-MR message reference, reported back in delivery report
+	Part   int       // Part index, starting from 0
+	Time   time.Time // Time of send confirmation
+
+	// Characters replaced because of SendOptions.Transliterate (the same
+	// for all parts)
+	Replaced []rune
+}
+
+// MsgClass specifies class of sent message
+type MsgClass int
+
+const (
+	DefaultClass MsgClass = iota // Class 1 for text, no class for 8-bit data
+	NoClass                      // Message without class
+	FlashClass                   // Class 0, displayed immediately
+	Class1                       // ME specific
+	Class2                       // SIM specific
+	Class3                       // TE specific
+)
+
+// gammu returns class number as used by libGammu (-1 means no class)
+func (c MsgClass) gammu(def int) int {
+	switch c {
+	case NoClass:
+		return -1
+	case FlashClass:
+		return 0
+	case Class1, Class2, Class3:
+		return int(c-Class1) + 1
+	}
+	return def
+}
+
+// SendOptions specifies optional parameters of sent message. nil
+// *SendOptions means default parameters.
+type SendOptions struct {
+	Report   bool          // Request delivery report
+	Class    MsgClass      // Message class
+	Validity time.Duration // Relative validity period (0 means SMSC default)
+	SMSC     string        // SMSC number (default: SMSC read from phone)
+
+	// National language tables (locking and single shift) that can be used
+	// to send text in fewer parts than using UCS-2. Single shift tables are
+	// used only if CanSendSingleShift returns true.
+	Languages []pdu.Language
+
+	// Replace characters from outside of GSM alphabet using
+	// pdu.Transliterate (before national language tables are considered).
+	Transliterate bool
+}
+
+func (sm *StateMachine) sendSMS(ctx context.Context, sms *C.GSM_SMSMessage, number string, opts *SendOptions) (res SendResult, err error) {
+	if opts.SMSC != "" {
+		decodeUTF8(&sms.SMSC.Number[0], opts.SMSC)
+	} else {
+		C.CopyUnicodeString(&sms.SMSC.Number[0], &sm.smsc.Number[0])
+	}
+	if opts.Validity > 0 {
+		sms.SMSC.Validity.Format = C.SMS_Validity_RelativeFormat
+		sms.SMSC.Validity.Relative = C.GSM_ValidityPeriod(
+			pdu.RelativeValidity(opts.Validity),
+		)
+	}
 	decodeUTF8(&sms.Number[0], number)
-	if report {
+	if opts.Report {
 		sms.PDU = C.SMS_Status_Report
 	} else {
 		sms.PDU = C.SMS_Submit
 	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
 	// Send mepssage
-	sm.status = C.ERR_TIMEOUT
+	sm.status.err = C.ERR_TIMEOUT
 	if e := C.GSM_SendSMS(sm.g, sms); e != C.ERR_NONE {
-		return Error{"SendSMS", e}
+		err = Error{"SendSMS", e}
+		return
 	}
-	// Wait for reply
+	// Wait for reply (status is changed by sendCallback)
 	t := time.Now()
-	for time.Now().Sub(t) < sm.Timeout {
-		C.GSM_ReadDevice(sm.g, C.TRUE)
-		if sm.status == C.ERR_NONE {
-			// Message sent OK
-			break
-		} else if sm.status != C.ERR_TIMEOUT {
-			// Error
-			break
+	for sm.status.err == C.ERR_TIMEOUT && time.Now().Sub(t) < sm.Timeout {
+		if err = ctx.Err(); err != nil {
+			return
 		}
+		C.GSM_ReadDevice(sm.g, C.TRUE)
 	}
-	if sm.status != C.ERR_NONE {
-		return Error{"ReadDevice", sm.status}
+	if sm.status.err != C.ERR_NONE {
+		err = Error{"ReadDevice", sm.status.err}
+		return
 	}
-	return nil
+	res.MsgRef = int(sm.status.msgRef)
+	res.Time = time.Now()
+	return
 }
 
-func (sm *StateMachine) SendSMS(number, text string, report bool) error {
+func (sm *StateMachine) SendSMS(number, text string, report bool) (SendResult, error) {
+	return sm.SendSMSContext(
+		context.Background(), number, text, &SendOptions{Report: report},
+	)
+}
+
+// SendSMSContext works like SendSMS but uses opts and stops waiting for the
+// send confirmation when ctx is done. It returns ctx.Err() in this case.
+func (sm *StateMachine) SendSMSContext(ctx context.Context, number, text string, opts *SendOptions) (SendResult, error) {
+	if opts == nil {
+		opts = new(SendOptions)
+	}
+	var replaced []rune
+	if opts.Transliterate {
+		text, replaced = pdu.Transliterate(text)
+	}
 	var sms C.GSM_SMSMessage
 	decodeUTF8(&sms.Text[0], text)
 	sms.UDH.Type = C.UDH_NoUDH
 	sms.Coding = C.SMS_Coding_Default_No_Compression
-	sms.Class = 1
-	return sm.sendSMS(&sms, number, report)
+	sms.Class = C.schar(opts.Class.gammu(1))
+	res, err := sm.sendSMS(ctx, &sms, number, opts)
+	res.Replaced = replaced
+	return res, err
+}
+
+// SendLongSMS sends text as concatenated message. It returns results for all
+// parts sent (for parts sent before an error too).
+func (sm *StateMachine) SendLongSMS(number, text string, report bool) ([]SendResult, error) {
+	return sm.SendLongSMSContext(
+		context.Background(), number, text, &SendOptions{Report: report},
+	)
+}
+
+// SendLongSMSContext works like SendLongSMS but uses opts and stops sending
+// when ctx is done. Parts sent before ctx was done aren't recalled.
+func (sm *StateMachine) SendLongSMSContext(ctx context.Context, number, text string, opts *SendOptions) ([]SendResult, error) {
+	if opts == nil {
+		opts = new(SendOptions)
+	}
+	var replaced []rune
+	if opts.Transliterate {
+		text, replaced = pdu.Transliterate(text)
+	}
+	res, err := sm.sendLongSMS(ctx, number, text, opts)
+	for i := range res {
+		res[i].Replaced = replaced
+	}
+	return res, err
 }
 
-func (sm *StateMachine) SendLongSMS(number, text string, report bool) error {
+func (sm *StateMachine) sendLongSMS(ctx context.Context, number, text string, opts *SendOptions) ([]SendResult, error) {
+	if len(opts.Languages) > 0 && !pdu.IsGSM7(text) {
+		sm.concatRef++
+		var single []pdu.Language
+		if CanSendSingleShift() {
+			single = opts.Languages
+		}
+		msgs, err := pdu.SplitTextTables(
+			pdu.Submit{}, text, sm.concatRef, opts.Languages, single,
+		)
+		if err != nil || len(msgs) > MaxParts {
+			return nil, EncodeError{C.ERR_MOREMEMORY}
+		}
+		if msgs[0].Coding == pdu.Coding7Bit {
+			return sm.sendNational(ctx, msgs, number, opts)
+		}
+	}
 	// Fill in SMS info
 	var smsInfo C.GSM_MultiPartSMSInfo
 	C.GSM_ClearMultiPartSMSInfo(&smsInfo)
-	smsInfo.Class = 1
+	smsInfo.Class = C.int(opts.Class.gammu(1))
 	smsInfo.EntriesNum = 1
 	smsInfo.UnicodeCoding = C.FALSE
-	// Check for non-ASCII rune
-	for _, r := range text {
-		if r > 0x7F {
-			smsInfo.UnicodeCoding = C.TRUE
-			break
-		}
+	// Use UCS-2 only if text doesn't fit in GSM 03.38 alphabet
+	if !pdu.IsGSM7(text) {
+		smsInfo.UnicodeCoding = C.TRUE
 	}
 	smsInfo.Entries[0].ID = C.SMS_ConcatenatedTextLong
 	msgUnicode := (*C.uchar)(C.calloc(C.size_t(len(text)+1), 2))
@@ -212,15 +344,44 @@ func (sm *StateMachine) SendLongSMS(number, text string, report bool) error {
 	// Prepare multipart message
 	var msms C.GSM_MultiSMSMessage
 	if e := C.GSM_EncodeMultiPartSMS(nil, &smsInfo, &msms); e != C.ERR_NONE {
-		return EncodeError{e}
+		return nil, EncodeError{e}
 	}
 	// Send message
+	res := make([]SendResult, 0, int(msms.Number))
 	for i := 0; i < int(msms.Number); i++ {
-		if e := sm.sendSMS(&msms.SMS[i], number, report); e != nil {
-			return e
+		r, err := sm.sendSMS(ctx, &msms.SMS[i], number, opts)
+		if err != nil {
+			return res, err
 		}
+		r.Part = i
+		res = append(res, r)
 	}
-	return nil
+	return res, nil
+}
+
+// sendNational sends 7-bit messages encoded using national language tables.
+func (sm *StateMachine) sendNational(ctx context.Context, msgs []pdu.Submit, number string, opts *SendOptions) ([]SendResult, error) {
+	res := make([]SendResult, 0, len(msgs))
+	for i, m := range msgs {
+		// libGammu encodes text using default alphabet so pass it text that
+		// has the same septets as m.Text in national tables
+		s, err := pdu.EncodeSeptets(m.UDH, m.Text)
+		if err != nil {
+			return res, EncodeError{C.ERR_UNKNOWN}
+		}
+		var sms C.GSM_SMSMessage
+		setUDH(&sms.UDH, m.UDH)
+		sms.Coding = C.SMS_Coding_Default_No_Compression
+		sms.Class = C.schar(opts.Class.gammu(1))
+		decodeUTF8(&sms.Text[0], gammuText(s))
+		r, err := sm.sendSMS(ctx, &sms, number, opts)
+		if err != nil {
+			return res, err
+		}
+		r.Part = i
+		res = append(res, r)
+	}
+	return res, nil
 }
 
 func encodeUTF8(in *C.uchar) string {
@@ -241,17 +402,193 @@ func goTime(t *C.GSM_DateTime) time.Time {
 	).Add(-time.Second * time.Duration(t.Timezone)).Local()
 }
 
+// Coding specifies how text of message is encoded
+type Coding int
+
+const (
+	CodingDefault           Coding = C.SMS_Coding_Default_No_Compression // GSM 03.38 alphabet
+	CodingDefaultCompressed Coding = C.SMS_Coding_Default_Compression
+	CodingUnicode           Coding = C.SMS_Coding_Unicode_No_Compression // UCS-2
+	CodingUnicodeCompressed Coding = C.SMS_Coding_Unicode_Compression
+	Coding8bit              Coding = C.SMS_Coding_8bit
+)
+
+func (c Coding) String() string {
+	switch c {
+	case CodingDefault:
+		return "default"
+	case CodingDefaultCompressed:
+		return "default-compressed"
+	case CodingUnicode:
+		return "unicode"
+	case CodingUnicodeCompressed:
+		return "unicode-compressed"
+	case Coding8bit:
+		return "8bit"
+	}
+	return "unknown"
+}
+
+// UDHType specifies type of User Data Header as recognized by libGammu
+type UDHType int
+
+const (
+	UDHNone           UDHType = C.UDH_NoUDH
+	UDHConcatenated   UDHType = C.UDH_ConcatenatedMessages
+	UDHConcatenated16 UDHType = C.UDH_ConcatenatedMessages16bit
+	UDHUser           UDHType = C.UDH_UserUDH // Not recognized by libGammu
+)
+
+// ReportState is category of TP-Status of delivery report
+type ReportState int
+
+const (
+	ReportDelivered   ReportState = iota // Message delivered (final)
+	ReportPending                        // SMSC still trying to deliver
+	ReportTempFailure                    // Temporary error, SMSC gave up (final)
+	ReportPermFailure                    // Permanent error (final)
+)
+
+func (s ReportState) String() string {
+	switch s {
+	case ReportDelivered:
+		return "delivered"
+	case ReportPending:
+		return "pending"
+	case ReportTempFailure:
+		return "temporary failure"
+	case ReportPermFailure:
+		return "permanent failure"
+	}
+	return "unknown"
+}
+
+// StatusReport contains decoded delivery report (SMS-STATUS-REPORT)
+type StatusReport struct {
+	MsgRef    int       // TP-MR of reported message
+	Status    int       // TP-Status code
+	Recipient string    // Recipient of reported message
+	Submitted time.Time // Time when SMSC received reported message
+	Discharge time.Time // Time of delivery or last delivery attempt
+}
+
+// State returns category of report status (GSM 03.40, 9.2.3.15)
+func (r *StatusReport) State() ReportState {
+	switch {
+	case r.Status < 0x20:
+		return ReportDelivered
+	case r.Status < 0x40:
+		return ReportPending
+	case r.Status < 0x60:
+		return ReportPermFailure
+	}
+	return ReportTempFailure
+}
+
+// Final returns true if SMSC doesn't make more attempts to deliver reported
+// message.
+func (r *StatusReport) Final() bool {
+	return r.State() != ReportPending
+}
+
 type SMS struct {
-	Time     time.Time
-	SMSCTime time.Time
-	Number   string
-	Report   bool // True if this message is a delivery report
-	Body     string
+	Time      time.Time
+	SMSCTime  time.Time
+	Number    string
+	Report    bool // True if this message is a delivery report
+	Body      string
+	Data      []byte     // Payload of 8-bit message
+	Location  int        // Location of (first part of) message in phone memory
+	Locations []int      // Locations of all parts of message
+	Folder    int        // Folder that contains message
+	Memory    MemoryType // Memory that contains message (SIM or phone)
+
+	SMSC      string  // SMSC number
+	Coding    Coding  // Coding of (first part of) message
+	Class     int     // Message class or -1 if not specified
+	UDH       UDHType // Type of UDH in (first part of) message
+	ConcatID  int     // Concatenated message ID or -1 if not concatenated
+	Part      int     // Number of (first) part in concatenated message
+	Parts     int     // Number of all parts in concatenated message
+	ReplyPath bool    // Reply via the same SMSC is requested
+	PID       int     // TP-PID (libGammu decodes only replace types)
+	Partial   bool    // Some parts of concatenated message are missing
+	DstPort   int     // Application destination port or -1
+	SrcPort   int     // Application source port or -1
+
+	StatusReport *StatusReport // Decoded delivery report or nil
+}
+
+func decodeSMS(msms *C.GSM_MultiSMSMessage) (sms SMS) {
+	s := &msms.SMS[msms.Number-1]
+	sms.Number = encodeUTF8(&s.Number[0])
+	sms.Time = goTime(&s.DateTime)
+	sms.SMSCTime = goTime(&s.SMSCTime)
+	s = &msms.SMS[0]
+	sms.Location = int(s.Location)
+	sms.Folder = int(s.Folder)
+	sms.Memory = MemoryType(s.Memory)
+	sms.SMSC = encodeUTF8(&s.SMSC.Number[0])
+	sms.Coding = Coding(s.Coding)
+	sms.Class = int(s.Class)
+	sms.UDH = UDHType(s.UDH.Type)
+	sms.ConcatID = -1
+	if s.UDH.ID16bit != -1 {
+		sms.ConcatID = int(s.UDH.ID16bit)
+	} else if s.UDH.ID8bit != -1 {
+		sms.ConcatID = int(s.UDH.ID8bit)
+	}
+	if sms.ConcatID != -1 {
+		sms.Part = int(s.UDH.PartNumber)
+		sms.Parts = int(s.UDH.AllParts)
+		sms.Partial = int(msms.Number) < sms.Parts
+	}
+	sms.ReplyPath = s.ReplyViaSameSMSC != 0
+	sms.DstPort, sms.SrcPort = udhPorts(&s.UDH)
+	if s.ReplaceMessage != 0 {
+		sms.PID = 0x40 + int(s.ReplaceMessage)
+	}
+
+	for i := 0; i < int(msms.Number); i++ {
+		s = &msms.SMS[i]
+		sms.Locations = append(sms.Locations, int(s.Location))
+		if s.Coding == C.SMS_Coding_8bit {
+			for k := 0; k < int(s.Length); k++ {
+				sms.Data = append(sms.Data, byte(s.Text[k]))
+			}
+			continue
+		}
+		text := encodeUTF8(&s.Text[0])
+		if s.Coding == C.SMS_Coding_Default_No_Compression {
+			text = nationalText(&s.UDH, text)
+		}
+		sms.Body += text
+		if s.PDU == C.SMS_Status_Report {
+			sms.Report = true
+			sms.StatusReport = &StatusReport{
+				MsgRef:    int(s.MessageReference),
+				Status:    int(s.DeliveryStatus),
+				Recipient: sms.Number,
+				Submitted: goTime(&s.DateTime),
+				Discharge: goTime(&s.SMSCTime),
+			}
+		}
+	}
+	return
 }
 
 // Read and deletes first avaliable message.
 // Returns io.EOF if there is no more messages to read
 func (sm *StateMachine) GetSMS() (sms SMS, err error) {
+	return sm.GetSMSContext(context.Background())
+}
+
+// GetSMSContext works like GetSMS but returns ctx.Err() without reading
+// anything if ctx is done.
+func (sm *StateMachine) GetSMSContext(ctx context.Context) (sms SMS, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
 	var msms C.GSM_MultiSMSMessage
 	if e := C.GSM_GetNextSMS(sm.g, &msms, C.TRUE); e != C.ERR_NONE {
 		if e == C.ERR_EMPTY {
@@ -261,20 +598,9 @@ func (sm *StateMachine) GetSMS() (sms SMS, err error) {
 		}
 		return
 	}
-	s := msms.SMS[msms.Number-1]
-	sms.Number = encodeUTF8(&s.Number[0])
-	sms.Time = goTime(&s.DateTime)
-	sms.SMSCTime = goTime(&s.SMSCTime)
-
+	sms = decodeSMS(&msms)
 	for i := 0; i < int(msms.Number); i++ {
-		s = msms.SMS[i]
-		if s.Coding == C.SMS_Coding_8bit {
-			continue
-		}
-		sms.Body += encodeUTF8(&s.Text[0])
-		if s.PDU == C.SMS_Status_Report {
-			sms.Report = true
-		}
+		s := msms.SMS[i]
 		s.Folder = 0 // Flat
 		if e := C.GSM_DeleteSMS(sm.g, &s); e != C.ERR_NONE {
 			err = Error{"DeleteSMS", e}
@@ -283,3 +609,81 @@ func (sm *StateMachine) GetSMS() (sms SMS, err error) {
 	}
 	return
 }
+
+// SMSList iterates over messages stored in the phone. Unlike GetSMS it
+// doesn't delete messages, use DeleteSMS for this.
+type SMSList struct {
+	sm       *StateMachine
+	folder   int
+	memory   MemoryType
+	start    bool
+	location C.int
+}
+
+// ListSMS returns iterator over messages stored in the specified folder or
+// in all folders if folder == 0. See GetSMSFolders for available folders.
+func (sm *StateMachine) ListSMS(folder int) *SMSList {
+	return &SMSList{sm: sm, folder: folder, start: true}
+}
+
+// Next returns next message from the list.
+// Returns io.EOF if there is no more messages to read
+func (l *SMSList) Next() (SMS, error) {
+	return l.NextContext(context.Background())
+}
+
+// NextContext works like Next but returns ctx.Err() without reading anything
+// if ctx is done.
+func (l *SMSList) NextContext(ctx context.Context) (sms SMS, err error) {
+	var msms C.GSM_MultiSMSMessage
+	if err = l.next(ctx, &msms); err != nil {
+		return
+	}
+	return decodeSMS(&msms), nil
+}
+
+func (l *SMSList) next(ctx context.Context, msms *C.GSM_MultiSMSMessage) (err error) {
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		*msms = C.GSM_MultiSMSMessage{}
+		start := C.gboolean(C.FALSE)
+		if l.start {
+			start = C.TRUE
+		} else {
+			msms.SMS[0].Location = l.location
+			msms.SMS[0].Folder = 0 // Flat
+		}
+		if e := C.GSM_GetNextSMS(l.sm.g, msms, start); e != C.ERR_NONE {
+			if e == C.ERR_EMPTY {
+				err = io.EOF
+			} else {
+				err = Error{"GetNextSMS", e}
+			}
+			return
+		}
+		l.start = false
+		l.location = msms.SMS[0].Location
+		if l.folder != 0 && int(msms.SMS[0].Folder) != l.folder {
+			continue
+		}
+		if l.memory != 0 && MemoryType(msms.SMS[0].Memory) != l.memory {
+			continue
+		}
+		return
+	}
+}
+
+// DeleteSMS deletes message from phone memory. Use Location of SMS returned
+// by SMSList and folder == 0 (flat memory) or real folder number if location
+// is relative to this folder.
+func (sm *StateMachine) DeleteSMS(location, folder int) error {
+	var s C.GSM_SMSMessage
+	s.Location = C.int(location)
+	s.Folder = C.int(folder)
+	if e := C.GSM_DeleteSMS(sm.g, &s); e != C.ERR_NONE {
+		return Error{"DeleteSMS", e}
+	}
+	return nil
+}
@@ -1,17 +1,22 @@
 package gammu
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"github.com/ziutek/gogammu/pdu"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
-var number string
+var number, device string
 
 func init() {
 	flag.StringVar(&number, "n", "", "phone number (required)")
+	flag.StringVar(&device, "d", "", "device for Config tests (eg. /dev/ttyUSB0)")
 	flag.Parse()
 	if number == "" {
 		flag.Usage()
@@ -29,8 +34,12 @@ func TestSend(t *testing.T) {
 	sm, err := NewStateMachine("")
 	checkErr(t, err)
 	checkErr(t, sm.Connect())
-	checkErr(t, sm.SendSMS(number, "Test1 ąśćźż", true))
-	checkErr(t, sm.SendLongSMS(number, "Test2 'ąśćźż' The Go programming language is an open source project to make programmers more productive.  Go is expressive, concise, clean, and efficient.", true))
+	res, err := sm.SendSMS(number, "Test1 ąśćźż", true)
+	checkErr(t, err)
+	fmt.Printf("sent SMS: %+v\n", res)
+	parts, err := sm.SendLongSMS(number, "Test2 'ąśćźż' The Go programming language is an open source project to make programmers more productive.  Go is expressive, concise, clean, and efficient.", true)
+	checkErr(t, err)
+	fmt.Printf("sent long SMS: %+v\n", parts)
 	checkErr(t, sm.Disconnect())
 }
 
@@ -48,3 +57,88 @@ func TestGet(t *testing.T) {
 	}
 	checkErr(t, sm.Disconnect())
 }
+
+func TestPhonebook(t *testing.T) {
+	sm, err := NewStateMachine("")
+	checkErr(t, err)
+	checkErr(t, sm.Connect())
+	e := PhonebookEntry{
+		Memory:  MemSIM,
+		Name:    "Test ąśćźż",
+		Numbers: []Number{{NumberMobile, number}},
+	}
+	checkErr(t, sm.AddMemory(&e))
+	r, err := sm.GetMemory(MemSIM, e.Location)
+	checkErr(t, err)
+	fmt.Printf("phonebook entry: %+v\n", r)
+	if r.Name != e.Name || len(r.Numbers) != 1 || r.Numbers[0].Number != number {
+		t.Errorf("bad entry: %+v", r)
+	}
+	vc, err := r.VCard()
+	checkErr(t, err)
+	entries, err := ParseVCards(vc)
+	checkErr(t, err)
+	if len(entries) != 1 || entries[0].Name != e.Name {
+		t.Errorf("bad vCard:\n%s\n%+v", vc, entries)
+	}
+	checkErr(t, sm.DeleteMemory(MemSIM, e.Location))
+	checkErr(t, sm.Disconnect())
+}
+
+func TestSafe(t *testing.T) {
+	sm, err := NewStateMachine("")
+	checkErr(t, err)
+	s := NewSafeStateMachine(sm)
+	defer s.Close()
+	checkErr(t, s.Connect())
+	done := make(chan error)
+	go func() {
+		_, err := s.SendSMS(number, "Test3 safe", false)
+		done <- err
+	}()
+	go func() {
+		_, err := s.GetSMSStatus()
+		done <- err
+	}()
+	checkErr(t, <-done)
+	checkErr(t, <-done)
+	checkErr(t, s.Disconnect())
+}
+
+func TestSendNational(t *testing.T) {
+	sm, err := NewStateMachine("")
+	checkErr(t, err)
+	checkErr(t, sm.Connect())
+	fmt.Println("single shift supported:", CanSendSingleShift())
+	opts := &SendOptions{
+		Languages: []pdu.Language{pdu.LangTurkish, pdu.LangSpanish},
+	}
+	parts, err := sm.SendLongSMSContext(
+		context.Background(), number,
+		"Test5 Canción, información y educación: "+
+			"ÁÍÓÚ áíóú ç. Şişli'de güzel bir gün geçirdik, çok teşekkürler!",
+		opts,
+	)
+	checkErr(t, err)
+	fmt.Printf("sent national SMS: %+v\n", parts)
+	checkErr(t, sm.Disconnect())
+}
+
+func TestConfigLog(t *testing.T) {
+	if device == "" {
+		t.Skip("no device (-d)")
+	}
+	dir, err := ioutil.TempDir("", "gammu")
+	checkErr(t, err)
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "gammu.log")
+	sm, err := NewStateMachineFromConfig(Config{Device: device, LogFile: log})
+	checkErr(t, err)
+	checkErr(t, sm.Connect())
+	checkErr(t, sm.Disconnect())
+	fi, err := os.Stat(log)
+	checkErr(t, err)
+	if fi.Size() == 0 {
+		t.Error("empty log file")
+	}
+}
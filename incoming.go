@@ -0,0 +1,62 @@
+package gammu
+
+/*
+#include <gammu.h>
+
+void goIncomingSMS(GSM_StateMachine *sm, GSM_SMSMessage *sms, void *data);
+
+static void setIncomingSMSCallback(GSM_StateMachine *sm) {
+	GSM_SetIncomingSMSCallback(sm, goIncomingSMS, NULL);
+}
+*/
+import "C"
+
+// IncomingSMS is message or delivery report reported by phone when it
+// arrives.
+type IncomingSMS struct {
+	SMS
+
+	// Stored is true if phone only notified that message was stored in its
+	// memory. Only Location, Folder and Memory are valid in this case and
+	// message should be read using GetSMS or ListSMS.
+	Stored bool
+
+	part *C.GSM_SMSMessage // Received part (nil if Stored)
+}
+
+func decodeIncoming(s *C.GSM_SMSMessage) (in IncomingSMS) {
+	if s.PDU == 0 {
+		// libGammu doesn't set PDU type for stored message notification
+		in.Stored = true
+		in.Location = int(s.Location)
+		in.Folder = int(s.Folder)
+		in.Memory = MemoryType(s.Memory)
+		return
+	}
+	in.part = new(C.GSM_SMSMessage)
+	*in.part = *s
+	var msms C.GSM_MultiSMSMessage
+	msms.Number = 1
+	msms.SMS[0] = *s
+	in.SMS = decodeSMS(&msms)
+	return
+}
+
+// SetIncomingSMS enables reporting of incoming messages and delivery reports
+// to c (or disables it if c == nil). It should be called after every Connect.
+// Events are received inside of StateMachine methods (eg. ReadDevice) and are
+// dropped if c isn't ready, so c should be buffered. Dropped messages that
+// phone stored in its memory can be still read using GetSMS, others are lost.
+// Use SMSAssembler.Add to join parts of concatenated messages.
+func (sm *StateMachine) SetIncomingSMS(c chan<- IncomingSMS) error {
+	setHandlers(sm.g, func(h *handlers) { h.sms = c })
+	enable := C.gboolean(C.FALSE)
+	if c != nil {
+		C.setIncomingSMSCallback(sm.g)
+		enable = C.TRUE
+	}
+	if e := C.GSM_SetIncomingSMS(sm.g, enable); e != C.ERR_NONE {
+		return Error{"SetIncomingSMS", e}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package gammu
+
+/*
+#include <gammu.h>
+*/
+import "C"
+
+// Size of buffers for identity strings (bigger than any GSM_MAX_*_LENGTH)
+const infoLen = 256
+
+// Manufacturer returns name of phone manufacturer.
+func (sm *StateMachine) Manufacturer() (string, error) {
+	var buf [infoLen]C.char
+	if e := C.GSM_GetManufacturer(sm.g, &buf[0]); e != C.ERR_NONE {
+		return "", Error{"GetManufacturer", e}
+	}
+	return C.GoString(&buf[0]), nil
+}
+
+// Model returns phone model.
+func (sm *StateMachine) Model() (string, error) {
+	var buf [infoLen]C.char
+	if e := C.GSM_GetModel(sm.g, &buf[0]); e != C.ERR_NONE {
+		return "", Error{"GetModel", e}
+	}
+	return C.GoString(&buf[0]), nil
+}
+
+// Firmware returns firmware version and its date (empty if unknown).
+func (sm *StateMachine) Firmware() (version, date string, err error) {
+	var ver, dt [infoLen]C.char
+	var num C.double
+	if e := C.GSM_GetFirmware(sm.g, &ver[0], &dt[0], &num); e != C.ERR_NONE {
+		err = Error{"GetFirmware", e}
+		return
+	}
+	return C.GoString(&ver[0]), C.GoString(&dt[0]), nil
+}
+
+// IMEI returns IMEI (serial number) of phone.
+func (sm *StateMachine) IMEI() (string, error) {
+	var buf [infoLen]C.char
+	if e := C.GSM_GetIMEI(sm.g, &buf[0]); e != C.ERR_NONE {
+		return "", Error{"GetIMEI", e}
+	}
+	return C.GoString(&buf[0]), nil
+}
+
+// SIMIMSI returns IMSI of SIM card.
+func (sm *StateMachine) SIMIMSI() (string, error) {
+	var buf [infoLen]C.char
+	if e := C.GSM_GetSIMIMSI(sm.g, &buf[0]); e != C.ERR_NONE {
+		return "", Error{"GetSIMIMSI", e}
+	}
+	return C.GoString(&buf[0]), nil
+}
@@ -0,0 +1,145 @@
+package gammu
+
+/*
+#include <stdlib.h>
+#include <gammu.h>
+
+GSM_MultiSMSMessage **allocMultiSMSList(int n) {
+	return (GSM_MultiSMSMessage **) calloc(n + 1, sizeof(GSM_MultiSMSMessage *));
+}
+void setMultiSMSList(GSM_MultiSMSMessage **l, int i, GSM_MultiSMSMessage *m) {
+	l[i] = m;
+}
+GSM_MultiSMSMessage *getMultiSMSList(GSM_MultiSMSMessage **l, int i) {
+	return l[i];
+}
+*/
+import "C"
+import (
+	"context"
+	"io"
+	"time"
+	"unsafe"
+)
+
+type smsPart struct {
+	sms   C.GSM_SMSMessage
+	added time.Time
+}
+
+// SMSAssembler joins parts of concatenated messages that are read from the
+// phone in different polls. It uses libGammu GSM_LinkSMS. Parts stay in the
+// phone until the caller deletes them (see SMS.Locations) so nothing is lost
+// if the program is restarted.
+type SMSAssembler struct {
+	Hold time.Duration // How long to wait for missing parts
+
+	parts  map[C.int]*smsPart // indexed by flat location
+	direct C.int              // last location assigned by Add
+}
+
+// NewSMSAssembler returns assembler that holds incomplete messages for hold
+// duration.
+func NewSMSAssembler(hold time.Duration) *SMSAssembler {
+	return &SMSAssembler{Hold: hold, parts: make(map[C.int]*smsPart)}
+}
+
+// Read reads all messages from l. Parts that are already known (read in
+// previous polls) are skipped.
+func (a *SMSAssembler) Read(l *SMSList) error {
+	return a.ReadContext(context.Background(), l)
+}
+
+// ReadContext works like Read but stops reading when ctx is done.
+func (a *SMSAssembler) ReadContext(ctx context.Context, l *SMSList) error {
+	now := time.Now()
+	var msms C.GSM_MultiSMSMessage
+	for {
+		if err := l.next(ctx, &msms); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		for i := 0; i < int(msms.Number); i++ {
+			s := &msms.SMS[i]
+			if _, ok := a.parts[s.Location]; !ok {
+				a.parts[s.Location] = &smsPart{sms: *s, added: now}
+			}
+		}
+	}
+}
+
+// Add adds part received by incoming SMS callback. Messages stored in the
+// phone are ignored (use Read for them). Other parts get negative locations:
+// they can't be deleted from phone because they aren't stored there.
+func (a *SMSAssembler) Add(in IncomingSMS) {
+	if in.part == nil {
+		return
+	}
+	a.direct--
+	p := &smsPart{sms: *in.part, added: time.Now()}
+	p.sms.Location = a.direct
+	a.parts[a.direct] = p
+}
+
+// Messages returns all complete messages and these incomplete messages (with
+// Partial field set) which waited for missing parts longer than Hold. Returned
+// messages are forgotten by assembler so they should be deleted from phone
+// (parts added by Add have negative locations and don't need this).
+func (a *SMSAssembler) Messages() ([]SMS, error) {
+	n := len(a.parts)
+	if n == 0 {
+		return nil, nil
+	}
+	in := C.allocMultiSMSList(C.int(n))
+	out := C.allocMultiSMSList(C.int(n))
+	if in == nil || out == nil {
+		panic("out of memory")
+	}
+	defer C.free(unsafe.Pointer(in))
+	defer C.free(unsafe.Pointer(out))
+	i := 0
+	for _, p := range a.parts {
+		m := (*C.GSM_MultiSMSMessage)(
+			C.calloc(1, C.size_t(unsafe.Sizeof(C.GSM_MultiSMSMessage{}))),
+		)
+		if m == nil {
+			panic("out of memory")
+		}
+		defer C.free(unsafe.Pointer(m))
+		m.Number = 1
+		m.SMS[0] = p.sms
+		C.setMultiSMSList(in, C.int(i), m)
+		i++
+	}
+	if e := C.GSM_LinkSMS(nil, in, out, C.FALSE); e != C.ERR_NONE {
+		return nil, Error{"LinkSMS", e}
+	}
+	var ret []SMS
+	now := time.Now()
+	for i := 0; ; i++ {
+		m := C.getMultiSMSList(out, C.int(i))
+		if m == nil {
+			break
+		}
+		sms := decodeSMS(m)
+		C.free(unsafe.Pointer(m))
+		if sms.Partial {
+			oldest := now
+			for _, l := range sms.Locations {
+				if p, ok := a.parts[C.int(l)]; ok && p.added.Before(oldest) {
+					oldest = p.added
+				}
+			}
+			if now.Sub(oldest) < a.Hold {
+				continue
+			}
+		}
+		for _, l := range sms.Locations {
+			delete(a.parts, C.int(l))
+		}
+		ret = append(ret, sms)
+	}
+	return ret, nil
+}
@@ -0,0 +1,118 @@
+package pdu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Types of address (TON and NPI octet)
+const (
+	TypeUnknown       = 0x81
+	TypeInternational = 0x91
+	TypeNational      = 0xa1
+	TypeAlphanumeric  = 0xd0
+)
+
+// Address is TP-OA, TP-DA or TP-RA address.
+type Address struct {
+	Type   byte   // Type of address (see Type* constants)
+	Number string // Digits (0-9 * # a b c) or text of alphanumeric address
+}
+
+// ParseAddress creates Address from phone number. International numbers
+// need to start with '+'. Strings that contain non-digits are treated as
+// alphanumeric addresses.
+func ParseAddress(s string) Address {
+	if strings.HasPrefix(s, "+") {
+		return Address{TypeInternational, s[1:]}
+	}
+	for _, r := range s {
+		if strings.IndexRune(semiOctets[:12], r) == -1 {
+			return Address{TypeAlphanumeric, s}
+		}
+	}
+	return Address{TypeUnknown, s}
+}
+
+// String returns number with '+' prefix for international numbers.
+func (a Address) String() string {
+	if a.Type&0x70 == TypeInternational&0x70 {
+		return "+" + a.Number
+	}
+	return a.Number
+}
+
+func (a Address) alphanumeric() bool {
+	return a.Type&0x70 == TypeAlphanumeric&0x70
+}
+
+const semiOctets = "0123456789*#abc"
+
+func (a Address) encode() ([]byte, error) {
+	typ := a.Type | 0x80
+	if a.alphanumeric() {
+		s, err := EncodeGSM7(a.Number)
+		if err != nil {
+			return nil, err
+		}
+		if len(s) > 11 {
+			return nil, fmt.Errorf("pdu: alphanumeric address too long: %s", a)
+		}
+		b := pack7(s, 0)
+		return append([]byte{byte((7*len(s) + 3) / 4), typ}, b...), nil
+	}
+	n := len(a.Number)
+	if n > 20 {
+		return nil, fmt.Errorf("pdu: address too long: %s", a)
+	}
+	b := make([]byte, 2+(n+1)/2)
+	b[0] = byte(n)
+	b[1] = typ
+	for i := 0; i < n; i++ {
+		d := strings.IndexByte(semiOctets, a.Number[i])
+		if d == -1 {
+			return nil, fmt.Errorf("pdu: bad character in address: %s", a)
+		}
+		if i%2 == 0 {
+			b[2+i/2] = 0xf0 | byte(d)
+		} else {
+			b[2+i/2] = b[2+i/2]&0x0f | byte(d)<<4
+		}
+	}
+	return b, nil
+}
+
+// decodeAddress decodes address from b and returns number of octets used.
+func decodeAddress(b []byte) (a Address, n int, err error) {
+	if len(b) < 2 {
+		err = ErrShort
+		return
+	}
+	l := int(b[0])
+	n = 2 + (l+1)/2
+	if len(b) < n {
+		err = ErrShort
+		return
+	}
+	a.Type = b[1]
+	if a.alphanumeric() {
+		var s []byte
+		s, err = unpack7(b[2:n], 0, 4*l/7)
+		a.Number = DecodeGSM7(s)
+		return
+	}
+	digits := make([]byte, 0, l)
+	for i := 0; i < l; i++ {
+		d := b[2+i/2]
+		if i%2 == 1 {
+			d >>= 4
+		}
+		d &= 0x0f
+		if d >= byte(len(semiOctets)) {
+			break // Filler
+		}
+		digits = append(digits, semiOctets[d])
+	}
+	a.Number = string(digits)
+	return
+}
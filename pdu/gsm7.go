@@ -0,0 +1,208 @@
+package pdu
+
+import "fmt"
+
+const esc = 0x1b // Escape to extension table
+
+// GSM 03.38 default alphabet. Position 0x1b (escape) is never decoded.
+var defaultAlphabet = []rune(
+	"@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞ\x1bÆæßÉ" +
+		" !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§" +
+		"¿abcdefghijklmnopqrstuvwxyzäöñüà",
+)
+
+// GSM 03.38 default alphabet extension table
+var defaultExtension = map[byte]rune{
+	0x0a: '\f',
+	0x14: '^',
+	0x28: '{',
+	0x29: '}',
+	0x2f: '\\',
+	0x3c: '[',
+	0x3d: '~',
+	0x3e: ']',
+	0x40: '|',
+	0x65: '€',
+}
+
+var (
+	defaultAlphabetRev  = reverse(defaultAlphabet)
+	defaultExtensionRev = reverseMap(defaultExtension)
+)
+
+func reverse(a []rune) map[rune]byte {
+	m := make(map[rune]byte, len(a))
+	for i, r := range a {
+		if i != esc {
+			m[r] = byte(i)
+		}
+	}
+	return m
+}
+
+func reverseMap(e map[byte]rune) map[rune]byte {
+	m := make(map[rune]byte, len(e))
+	for c, r := range e {
+		m[r] = c
+	}
+	return m
+}
+
+// charset is pair of tables used to encode/decode text: basic (default
+// alphabet or national locking shift table) and extension (default
+// extension table or national single shift table).
+type charset struct {
+	basic    []rune
+	ext      map[byte]rune
+	basicRev map[rune]byte
+	extRev   map[rune]byte
+}
+
+var defaultCharset = &charset{
+	defaultAlphabet, defaultExtension, defaultAlphabetRev, defaultExtensionRev,
+}
+
+// runeLen returns number of septets used to encode r or 0 if r can't be
+// encoded.
+func (cs *charset) runeLen(r rune) int {
+	if _, ok := cs.basicRev[r]; ok {
+		return 1
+	}
+	if _, ok := cs.extRev[r]; ok {
+		return 2
+	}
+	return 0
+}
+
+// septetLen returns number of septets used to encode text or -1 if text
+// can't be encoded.
+func (cs *charset) septetLen(text string) int {
+	n := 0
+	for _, r := range text {
+		l := cs.runeLen(r)
+		if l == 0 {
+			return -1
+		}
+		n += l
+	}
+	return n
+}
+
+// appendRune appends septets that encode r to s.
+func (cs *charset) appendRune(s []byte, r rune) ([]byte, error) {
+	if c, ok := cs.basicRev[r]; ok {
+		return append(s, c), nil
+	}
+	if c, ok := cs.extRev[r]; ok {
+		return append(s, esc, c), nil
+	}
+	return s, CharError{r}
+}
+
+func (cs *charset) encode(text string) ([]byte, error) {
+	s := make([]byte, 0, len(text))
+	var err error
+	for _, r := range text {
+		if s, err = cs.appendRune(s, r); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (cs *charset) decode(septets []byte) string {
+	text := make([]rune, 0, len(septets))
+	for i := 0; i < len(septets); i++ {
+		c := septets[i] & 0x7f
+		if c == esc && i+1 < len(septets) {
+			i++
+			c = septets[i] & 0x7f
+			if r, ok := cs.ext[c]; ok {
+				text = append(text, r)
+				continue
+			}
+			// Unknown extension: use basic table (GSM 03.38, 6.2.1.1)
+		}
+		if c == esc {
+			text = append(text, ' ')
+			continue
+		}
+		text = append(text, cs.basic[c])
+	}
+	return string(text)
+}
+
+// CharError is returned when character can't be encoded using GSM 03.38
+// alphabet.
+type CharError struct {
+	Char rune
+}
+
+func (e CharError) Error() string {
+	return fmt.Sprintf("pdu: character %q isn't in GSM 03.38 alphabet", e.Char)
+}
+
+// GSM7Len returns number of septets used to encode r in GSM 03.38 alphabet:
+// 1 for characters from default alphabet, 2 for characters from extension
+// table (escaped) or 0 if r can't be encoded.
+func GSM7Len(r rune) int {
+	return defaultCharset.runeLen(r)
+}
+
+// IsGSM7 reports whether text can be encoded using GSM 03.38 default
+// alphabet and its extension table.
+func IsGSM7(text string) bool {
+	return defaultCharset.septetLen(text) >= 0
+}
+
+// SeptetLen returns number of septets needed to encode text using GSM 03.38
+// alphabet or -1 if text contains characters outside of this alphabet.
+func SeptetLen(text string) int {
+	return defaultCharset.septetLen(text)
+}
+
+// EncodeGSM7 converts text to septets (one septet per byte) using GSM 03.38
+// default alphabet and its extension table.
+func EncodeGSM7(text string) ([]byte, error) {
+	return defaultCharset.encode(text)
+}
+
+// DecodeGSM7 converts septets (one septet per byte) to text.
+func DecodeGSM7(septets []byte) string {
+	return defaultCharset.decode(septets)
+}
+
+// pack7 packs septets into octets starting from bit off of returned buffer.
+// Bits before off are zero.
+func pack7(septets []byte, off int) []byte {
+	b := make([]byte, (off+7*len(septets)+7)/8)
+	for _, s := range septets {
+		s &= 0x7f
+		i, n := off/8, uint(off%8)
+		b[i] |= s << n
+		if n > 1 {
+			b[i+1] |= s >> (8 - n)
+		}
+		off += 7
+	}
+	return b
+}
+
+// unpack7 unpacks n septets from b starting from bit off.
+func unpack7(b []byte, off, n int) ([]byte, error) {
+	if off+7*n > 8*len(b) {
+		return nil, ErrShort
+	}
+	s := make([]byte, n)
+	for k := range s {
+		i, m := off/8, uint(off%8)
+		c := b[i] >> m
+		if m > 1 {
+			c |= b[i+1] << (8 - m)
+		}
+		s[k] = c & 0x7f
+		off += 7
+	}
+	return s, nil
+}
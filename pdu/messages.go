@@ -0,0 +1,334 @@
+package pdu
+
+import (
+	"fmt"
+	"time"
+)
+
+// Submit is SMS-SUBMIT TPDU (message sent to SMSC)
+type Submit struct {
+	RejectDuplicates bool
+	ReplyPath        bool
+	StatusReport     bool // Status report request
+	MsgRef           byte
+	Recipient        Address
+	PID              byte
+	Coding           Coding
+	HasClass         bool
+	Class            int           // Message class (valid if HasClass)
+	Validity         time.Duration // Relative validity period, 0 if not present
+	UDH              UDH
+	Text             string // Message text (7-bit and UCS-2 coding)
+	Data             []byte // Message data (8-bit coding)
+}
+
+// MarshalBinary encodes m.
+func (m *Submit) MarshalBinary() ([]byte, error) {
+	b := []byte{MTISubmit}
+	if m.RejectDuplicates {
+		b[0] |= 0x04
+	}
+	if m.Validity > 0 {
+		b[0] |= 0x10 // Relative format
+	}
+	if m.StatusReport {
+		b[0] |= 0x20
+	}
+	if len(m.UDH) > 0 {
+		b[0] |= 0x40
+	}
+	if m.ReplyPath {
+		b[0] |= 0x80
+	}
+	b = append(b, m.MsgRef)
+	a, err := m.Recipient.encode()
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, a...)
+	b = append(b, m.PID, encodeDCS(m.Coding, m.Class, m.HasClass))
+	if m.Validity > 0 {
+		b = append(b, RelativeValidity(m.Validity))
+	}
+	udl, ud, err := encodeUD(m.Coding, m.UDH, m.Text, m.Data)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, udl)
+	return append(b, ud...), nil
+}
+
+// UnmarshalBinary decodes b into m. Validity periods in absolute and
+// enhanced format are skipped.
+func (m *Submit) UnmarshalBinary(b []byte) error {
+	if len(b) < 2 {
+		return ErrShort
+	}
+	if b[0]&3 != MTISubmit {
+		return fmt.Errorf("pdu: not SMS-SUBMIT (MTI %d)", b[0]&3)
+	}
+	*m = Submit{
+		RejectDuplicates: b[0]&0x04 != 0,
+		StatusReport:     b[0]&0x20 != 0,
+		ReplyPath:        b[0]&0x80 != 0,
+		MsgRef:           b[1],
+	}
+	vpf, udhi := b[0]>>3&3, b[0]&0x40 != 0
+	a, n, err := decodeAddress(b[2:])
+	if err != nil {
+		return err
+	}
+	m.Recipient = a
+	b = b[2+n:]
+	if len(b) < 2 {
+		return ErrShort
+	}
+	m.PID = b[0]
+	m.Coding, m.Class, m.HasClass, err = decodeDCS(b[1])
+	if err != nil {
+		return err
+	}
+	b = b[2:]
+	switch vpf {
+	case 2:
+		if len(b) < 1 {
+			return ErrShort
+		}
+		m.Validity = ValidityPeriod(b[0])
+		b = b[1:]
+	case 1, 3:
+		if len(b) < 7 {
+			return ErrShort
+		}
+		b = b[7:]
+	}
+	if len(b) < 1 {
+		return ErrShort
+	}
+	m.UDH, m.Text, m.Data, err = decodeUD(m.Coding, udhi, int(b[0]), b[1:])
+	return err
+}
+
+// Deliver is SMS-DELIVER TPDU (message received from SMSC)
+type Deliver struct {
+	MoreMessages bool // More messages are waiting in SMSC
+	ReplyPath    bool
+	StatusReport bool // Status report will be returned to sender
+	Sender       Address
+	PID          byte
+	Coding       Coding
+	HasClass     bool
+	Class        int       // Message class (valid if HasClass)
+	Time         time.Time // Service centre time stamp
+	UDH          UDH
+	Text         string // Message text (7-bit and UCS-2 coding)
+	Data         []byte // Message data (8-bit coding)
+}
+
+// MarshalBinary encodes m.
+func (m *Deliver) MarshalBinary() ([]byte, error) {
+	b := []byte{MTIDeliver}
+	if !m.MoreMessages {
+		b[0] |= 0x04
+	}
+	if m.StatusReport {
+		b[0] |= 0x20
+	}
+	if len(m.UDH) > 0 {
+		b[0] |= 0x40
+	}
+	if m.ReplyPath {
+		b[0] |= 0x80
+	}
+	a, err := m.Sender.encode()
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, a...)
+	b = append(b, m.PID, encodeDCS(m.Coding, m.Class, m.HasClass))
+	b = append(b, encodeTime(m.Time)...)
+	udl, ud, err := encodeUD(m.Coding, m.UDH, m.Text, m.Data)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, udl)
+	return append(b, ud...), nil
+}
+
+// UnmarshalBinary decodes b into m.
+func (m *Deliver) UnmarshalBinary(b []byte) error {
+	if len(b) < 1 {
+		return ErrShort
+	}
+	if b[0]&3 != MTIDeliver {
+		return fmt.Errorf("pdu: not SMS-DELIVER (MTI %d)", b[0]&3)
+	}
+	*m = Deliver{
+		MoreMessages: b[0]&0x04 == 0,
+		StatusReport: b[0]&0x20 != 0,
+		ReplyPath:    b[0]&0x80 != 0,
+	}
+	udhi := b[0]&0x40 != 0
+	a, n, err := decodeAddress(b[1:])
+	if err != nil {
+		return err
+	}
+	m.Sender = a
+	b = b[1+n:]
+	if len(b) < 10 {
+		return ErrShort
+	}
+	m.PID = b[0]
+	m.Coding, m.Class, m.HasClass, err = decodeDCS(b[1])
+	if err != nil {
+		return err
+	}
+	if m.Time, err = decodeTime(b[2:9]); err != nil {
+		return err
+	}
+	m.UDH, m.Text, m.Data, err = decodeUD(m.Coding, udhi, int(b[9]), b[10:])
+	return err
+}
+
+// StatusReport is SMS-STATUS-REPORT TPDU
+type StatusReport struct {
+	MoreMessages bool // More messages are waiting in SMSC
+	MsgRef       byte // TP-MR of reported message
+	Recipient    Address
+	Time         time.Time // Time when SMSC received reported message
+	Discharge    time.Time // Time of delivery or last delivery attempt
+	Status       byte      // TP-ST
+
+	// Optional parameters
+	PID      byte
+	Coding   Coding
+	HasClass bool
+	Class    int
+	UDH      UDH
+	Text     string
+	Data     []byte
+}
+
+// Delivered returns true if reported message was delivered.
+func (m *StatusReport) Delivered() bool {
+	return m.Status < 0x20
+}
+
+// Final returns true if SMSC makes no more attempts to deliver reported
+// message.
+func (m *StatusReport) Final() bool {
+	return m.Status < 0x20 || m.Status >= 0x40
+}
+
+// MarshalBinary encodes m. Optional parameters are encoded only if they
+// aren't zero.
+func (m *StatusReport) MarshalBinary() ([]byte, error) {
+	b := []byte{MTIStatusReport}
+	if !m.MoreMessages {
+		b[0] |= 0x04
+	}
+	if len(m.UDH) > 0 {
+		b[0] |= 0x40
+	}
+	b = append(b, m.MsgRef)
+	a, err := m.Recipient.encode()
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, a...)
+	b = append(b, encodeTime(m.Time)...)
+	b = append(b, encodeTime(m.Discharge)...)
+	b = append(b, m.Status)
+	ud := len(m.UDH) > 0 || m.Text != "" || len(m.Data) > 0
+	dcs := ud || m.Coding != Coding7Bit || m.HasClass
+	if m.PID == 0 && !dcs {
+		return b, nil
+	}
+	pi := byte(0)
+	if m.PID != 0 {
+		pi |= 0x01
+	}
+	if dcs {
+		pi |= 0x02
+	}
+	if ud {
+		pi |= 0x04
+	}
+	b = append(b, pi)
+	if m.PID != 0 {
+		b = append(b, m.PID)
+	}
+	if dcs {
+		b = append(b, encodeDCS(m.Coding, m.Class, m.HasClass))
+	}
+	if ud {
+		udl, d, err := encodeUD(m.Coding, m.UDH, m.Text, m.Data)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, udl)
+		b = append(b, d...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes b into m.
+func (m *StatusReport) UnmarshalBinary(b []byte) error {
+	if len(b) < 2 {
+		return ErrShort
+	}
+	if b[0]&3 != MTIStatusReport {
+		return fmt.Errorf("pdu: not SMS-STATUS-REPORT (MTI %d)", b[0]&3)
+	}
+	*m = StatusReport{
+		MoreMessages: b[0]&0x04 == 0,
+		MsgRef:       b[1],
+	}
+	udhi := b[0]&0x40 != 0
+	a, n, err := decodeAddress(b[2:])
+	if err != nil {
+		return err
+	}
+	m.Recipient = a
+	b = b[2+n:]
+	if len(b) < 15 {
+		return ErrShort
+	}
+	if m.Time, err = decodeTime(b[0:7]); err != nil {
+		return err
+	}
+	if m.Discharge, err = decodeTime(b[7:14]); err != nil {
+		return err
+	}
+	m.Status = b[14]
+	b = b[15:]
+	if len(b) == 0 {
+		return nil
+	}
+	pi := b[0]
+	b = b[1:]
+	if pi&0x01 != 0 {
+		if len(b) < 1 {
+			return ErrShort
+		}
+		m.PID = b[0]
+		b = b[1:]
+	}
+	if pi&0x02 != 0 {
+		if len(b) < 1 {
+			return ErrShort
+		}
+		m.Coding, m.Class, m.HasClass, err = decodeDCS(b[0])
+		if err != nil {
+			return err
+		}
+		b = b[1:]
+	}
+	if pi&0x04 != 0 {
+		if len(b) < 1 {
+			return ErrShort
+		}
+		m.UDH, m.Text, m.Data, err = decodeUD(m.Coding, udhi, int(b[0]), b[1:])
+	}
+	return err
+}
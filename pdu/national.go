@@ -0,0 +1,165 @@
+package pdu
+
+import "fmt"
+
+// Language identifies national language shift tables (3GPP TS 23.038,
+// 6.2.1.2.4). Only languages written in Latin script with tables that differ
+// from default alphabet are supported. There is no table for Polish.
+type Language byte
+
+const (
+	LangDefault    Language = 0 // GSM 03.38 default alphabet / extension
+	LangTurkish    Language = 1
+	LangSpanish    Language = 2 // Single shift table only
+	LangPortuguese Language = 3
+)
+
+func (l Language) String() string {
+	switch l {
+	case LangDefault:
+		return "default"
+	case LangTurkish:
+		return "turkish"
+	case LangSpanish:
+		return "spanish"
+	case LangPortuguese:
+		return "portuguese"
+	}
+	return fmt.Sprintf("Language(%d)", byte(l))
+}
+
+// ParseLanguage returns language with specified name (as returned by
+// String method).
+func ParseLanguage(name string) (Language, bool) {
+	for _, l := range []Language{
+		LangDefault, LangTurkish, LangSpanish, LangPortuguese,
+	} {
+		if l.String() == name {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// National language locking shift tables (replace default alphabet)
+var lockingShift = map[Language][]rune{
+	LangTurkish: []rune(
+		"@£$¥€éùıòÇ\nĞğ\rÅåΔ_ΦΓΛΩΠΨΣΘΞ\x1bŞşßÉ" +
+			" !\"#¤%&'()*+,-./0123456789:;<=>?" +
+			"İABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§" +
+			"çabcdefghijklmnopqrstuvwxyzäöñüà",
+	),
+	LangPortuguese: []rune(
+		"@£$¥êéúíóç\nÔô\rÁáΔ_ªÇÀ∞^\\€Ó|\x1bÂâÊÉ" +
+			" !\"#º%&'()*+,-./0123456789:;<=>?" +
+			"ÍABCDEFGHIJKLMNOPQRSTUVWXYZÃÕÚÜ§" +
+			"~abcdefghijklmnopqrstuvwxyzãõ`üà",
+	),
+}
+
+// National language single shift tables (replace default extension table)
+var singleShift = map[Language]map[byte]rune{
+	LangTurkish: {
+		0x0a: '\f', 0x14: '^', 0x28: '{', 0x29: '}', 0x2f: '\\',
+		0x3c: '[', 0x3d: '~', 0x3e: ']', 0x40: '|',
+		0x47: 'Ğ', 0x49: 'İ', 0x53: 'Ş', 0x63: 'ç', 0x65: '€',
+		0x67: 'ğ', 0x69: 'ı', 0x73: 'ş',
+	},
+	LangSpanish: {
+		0x09: 'ç', 0x0a: '\f', 0x14: '^', 0x28: '{', 0x29: '}',
+		0x2f: '\\', 0x3c: '[', 0x3d: '~', 0x3e: ']', 0x40: '|',
+		0x41: 'Á', 0x49: 'Í', 0x4f: 'Ó', 0x55: 'Ú', 0x61: 'á',
+		0x65: '€', 0x69: 'í', 0x6f: 'ó', 0x75: 'ú',
+	},
+	LangPortuguese: {
+		0x05: 'ê', 0x09: 'ç', 0x0a: '\f', 0x0b: 'Ô', 0x0c: 'ô',
+		0x0e: 'Á', 0x0f: 'á', 0x12: 'Φ', 0x13: 'Γ', 0x14: '^',
+		0x15: 'Ω', 0x16: 'Π', 0x17: 'Ψ', 0x18: 'Σ', 0x19: 'Θ',
+		0x1f: 'Ê', 0x28: '{', 0x29: '}', 0x2f: '\\', 0x3c: '[',
+		0x3d: '~', 0x3e: ']', 0x40: '|', 0x41: 'À', 0x49: 'Í',
+		0x4f: 'Ó', 0x55: 'Ú', 0x5b: 'Ã', 0x5c: 'Õ', 0x61: 'Â',
+		0x65: '€', 0x69: 'í', 0x6f: 'ó', 0x75: 'ú', 0x7b: 'ã',
+		0x7c: 'õ', 0x7f: 'â',
+	},
+}
+
+var (
+	lockingShiftRev = make(map[Language]map[rune]byte)
+	singleShiftRev  = make(map[Language]map[rune]byte)
+)
+
+func init() {
+	for l, t := range lockingShift {
+		lockingShiftRev[l] = reverse(t)
+	}
+	for l, t := range singleShift {
+		singleShiftRev[l] = reverseMap(t)
+	}
+}
+
+// HasLockingShift reports whether there is locking shift table for l.
+func HasLockingShift(l Language) bool {
+	_, ok := lockingShift[l]
+	return ok
+}
+
+// HasSingleShift reports whether there is single shift table for l.
+func HasSingleShift(l Language) bool {
+	_, ok := singleShift[l]
+	return ok
+}
+
+// newCharset returns charset that uses locking and single shift tables.
+// Unknown languages are replaced by default tables (GSM 03.38, 6.2.1.2.4).
+func newCharset(locking, single Language) *charset {
+	cs := *defaultCharset
+	if t, ok := lockingShift[locking]; ok {
+		cs.basic, cs.basicRev = t, lockingShiftRev[locking]
+	}
+	if t, ok := singleShift[single]; ok {
+		cs.ext, cs.extRev = t, singleShiftRev[single]
+	}
+	return &cs
+}
+
+// charsetFor returns charset selected by national language IEs in h.
+func charsetFor(h UDH) *charset {
+	locking, single := h.Shift()
+	if locking == LangDefault && single == LangDefault {
+		return defaultCharset
+	}
+	return newCharset(locking, single)
+}
+
+// LockingShiftIE returns national language locking shift element.
+func LockingShiftIE(l Language) IE {
+	return IE{IELockingShift, []byte{byte(l)}}
+}
+
+// SingleShiftIE returns national language single shift element.
+func SingleShiftIE(l Language) IE {
+	return IE{IESingleShift, []byte{byte(l)}}
+}
+
+// Shift returns languages of locking and single shift tables selected by h.
+func (h UDH) Shift() (locking, single Language) {
+	if ie, ok := h.Get(IELockingShift); ok && len(ie.Data) == 1 {
+		locking = Language(ie.Data[0])
+	}
+	if ie, ok := h.Get(IESingleShift); ok && len(ie.Data) == 1 {
+		single = Language(ie.Data[0])
+	}
+	return
+}
+
+// EncodeSeptets works like EncodeGSM7 but uses national language tables
+// selected by h.
+func EncodeSeptets(h UDH, text string) ([]byte, error) {
+	return charsetFor(h).encode(text)
+}
+
+// DecodeSeptets works like DecodeGSM7 but uses national language tables
+// selected by h.
+func DecodeSeptets(h UDH, septets []byte) string {
+	return charsetFor(h).decode(septets)
+}
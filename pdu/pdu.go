@@ -0,0 +1,105 @@
+// Package pdu encodes and decodes SMS TPDUs (GSM 03.40 / 3GPP 23.040):
+// SMS-SUBMIT, SMS-DELIVER and SMS-STATUS-REPORT, including User Data Header,
+// concatenated messages and 7-bit, 8-bit and UCS-2 codings. It is written
+// entirely in Go, so it doesn't depend on libGammu.
+//
+// TPDUs don't contain SMSC address which is prepended by some devices (eg.
+// in AT+CMGS PDU mode).
+package pdu
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrShort   = errors.New("pdu: TPDU too short")
+	ErrTooLong = errors.New("pdu: user data too long")
+)
+
+// Coding is alphabet used to encode user data
+type Coding byte
+
+const (
+	Coding7Bit Coding = iota // GSM 03.38 default alphabet
+	Coding8Bit               // Binary data
+	CodingUCS2               // UCS-2 (UTF-16BE)
+)
+
+func (c Coding) String() string {
+	switch c {
+	case Coding7Bit:
+		return "7bit"
+	case Coding8Bit:
+		return "8bit"
+	case CodingUCS2:
+		return "ucs2"
+	}
+	return fmt.Sprintf("Coding(%d)", byte(c))
+}
+
+// encodeDCS returns TP-DCS in general data coding group.
+func encodeDCS(c Coding, class int, hasClass bool) byte {
+	dcs := byte(c) << 2
+	if hasClass {
+		dcs |= 0x10 | byte(class&3)
+	}
+	return dcs
+}
+
+// decodeDCS decodes TP-DCS (GSM 03.38, 4).
+func decodeDCS(dcs byte) (c Coding, class int, hasClass bool, err error) {
+	switch dcs >> 4 {
+	case 0, 1, 2, 3, 4, 5, 6, 7: // General data coding, automatic deletion
+		if dcs&0x20 != 0 {
+			err = fmt.Errorf("pdu: compressed data (DCS 0x%02x)", dcs)
+			return
+		}
+		c = Coding(dcs >> 2 & 3)
+		if c > CodingUCS2 {
+			c = Coding7Bit // Reserved
+		}
+		hasClass = dcs&0x10 != 0
+	case 0xc, 0xd: // Message waiting indication
+		c = Coding7Bit
+	case 0xe: // Message waiting indication, UCS-2
+		c = CodingUCS2
+	case 0xf: // Data coding / message class
+		c = Coding(dcs >> 2 & 1)
+		hasClass = true
+	default: // Reserved
+		c = Coding7Bit
+	}
+	if hasClass {
+		class = int(dcs & 3)
+	}
+	return
+}
+
+// Message types (TP-MTI)
+const (
+	MTIDeliver      = 0
+	MTISubmit       = 1
+	MTIStatusReport = 2
+)
+
+// Decode decodes TPDU received from SMSC (SMS-DELIVER or SMS-STATUS-REPORT)
+// or sent to SMSC (SMS-SUBMIT). It returns *Deliver, *Submit or
+// *StatusReport.
+func Decode(b []byte) (interface{}, error) {
+	if len(b) == 0 {
+		return nil, ErrShort
+	}
+	switch b[0] & 3 {
+	case MTIDeliver:
+		m := new(Deliver)
+		return m, m.UnmarshalBinary(b)
+	case MTISubmit:
+		m := new(Submit)
+		return m, m.UnmarshalBinary(b)
+	case MTIStatusReport:
+		m := new(StatusReport)
+		return m, m.UnmarshalBinary(b)
+	}
+	return nil, fmt.Errorf("pdu: unknown message type %d", b[0]&3)
+}
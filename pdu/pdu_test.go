@@ -0,0 +1,369 @@
+package pdu
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeDeliver(t *testing.T) {
+	b := unhex(t, "040BC87238880900F10000993092516195800AE8329BFD4697D9EC37")
+	var m Deliver
+	if err := m.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if m.Sender.Number != "27838890001" || m.Sender.Type != 0xc8 {
+		t.Errorf("bad sender: %+v", m.Sender)
+	}
+	if m.Text != "hellohello" {
+		t.Errorf("bad text: %q", m.Text)
+	}
+	tm := time.Date(1999, 3, 29, 15, 16, 59, 0, time.FixedZone("", 2*3600))
+	if !m.Time.Equal(tm) {
+		t.Errorf("bad time: %s", m.Time)
+	}
+	if m.HasClass || m.Coding != Coding7Bit {
+		t.Errorf("bad DCS: %+v", m)
+	}
+}
+
+func TestEncodeSubmit(t *testing.T) {
+	m := Submit{
+		Recipient: ParseAddress("+46708251358"),
+		Validity:  4 * 24 * time.Hour,
+		Text:      "hellohello",
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := unhex(t, "11000B916407281553F80000AA0AE8329BFD4697D9EC37")
+	if !bytes.Equal(b, exp) {
+		t.Fatalf("\nexp: %X\ngot: %X", exp, b)
+	}
+	var d Submit
+	if err = d.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if d.Recipient.String() != "+46708251358" || d.Text != m.Text ||
+		d.Validity != m.Validity {
+		t.Errorf("bad decoded message: %+v", d)
+	}
+}
+
+func TestStatusReport(t *testing.T) {
+	tz := time.FixedZone("", -5*3600/2) // -2.5h
+	m := StatusReport{
+		MsgRef:    0x42,
+		Recipient: ParseAddress("+48601234567"),
+		Time:      time.Date(2013, 5, 1, 12, 30, 15, 0, tz),
+		Discharge: time.Date(2013, 5, 1, 12, 30, 45, 0, tz),
+		Status:    0x41,
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := v.(*StatusReport)
+	if !ok {
+		t.Fatalf("bad type: %T", v)
+	}
+	if d.MsgRef != m.MsgRef || d.Recipient != m.Recipient ||
+		!d.Time.Equal(m.Time) || !d.Discharge.Equal(m.Discharge) {
+		t.Errorf("\nexp: %+v\ngot: %+v", m, *d)
+	}
+	if d.Delivered() || !d.Final() {
+		t.Errorf("bad status: 0x%02x", d.Status)
+	}
+}
+
+func TestAlphanumericAddress(t *testing.T) {
+	m := Deliver{
+		Sender: ParseAddress("Bank"),
+		Text:   "Kod: 1234",
+		UDH:    UDH{PortsIE(2948, 9200)},
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Deliver
+	if err = d.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if d.Sender != m.Sender || d.Text != m.Text {
+		t.Errorf("\nexp: %+v\ngot: %+v", m, d)
+	}
+	if dst, src, ok := d.UDH.Ports(); !ok || dst != 2948 || src != 9200 {
+		t.Errorf("bad ports: %d %d %t", dst, src, ok)
+	}
+}
+
+func TestGSM7(t *testing.T) {
+	text := "@£$ Ünïcode? no: {ok} [€] ~\\|^"
+	_, err := EncodeGSM7(text)
+	if _, ok := err.(CharError); !ok {
+		t.Fatal("ï encoded in GSM alphabet")
+	}
+	text = strings.Replace(text, "ï", "i", 1)
+	s, err := EncodeGSM7(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != len([]rune(text))+9 {
+		t.Errorf("bad number of septets: %d", len(s))
+	}
+	for fill := 0; fill < 7; fill++ {
+		u, err := unpack7(pack7(s, fill), fill, len(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := DecodeGSM7(u); d != text {
+			t.Errorf("fill=%d: %q != %q", fill, d, text)
+		}
+	}
+}
+
+func checkSplit(t *testing.T, text string, c Coding, n int) {
+	msgs, err := SplitText(Submit{Recipient: ParseAddress("123")}, text, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != n {
+		t.Fatalf("%d parts, expected %d", len(msgs), n)
+	}
+	joined := ""
+	for i, m := range msgs {
+		if m.Coding != c {
+			t.Errorf("part %d: coding %s", i, m.Coding)
+		}
+		b, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatalf("part %d: %s", i, err)
+		}
+		var d Submit
+		if err = d.UnmarshalBinary(b); err != nil {
+			t.Fatalf("part %d: %s", i, err)
+		}
+		if n > 1 {
+			ref, parts, part, ok := d.UDH.Concat()
+			if !ok || ref != 7 || parts != n || part != i+1 {
+				t.Errorf("part %d: bad UDH: %v", i, d.UDH)
+			}
+		}
+		joined += d.Text
+	}
+	if joined != text {
+		t.Errorf("\nexp: %q\ngot: %q", text, joined)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	checkSplit(t, strings.Repeat("a", 160), Coding7Bit, 1)
+	checkSplit(t, strings.Repeat("a", 161), Coding7Bit, 2)
+	checkSplit(t, strings.Repeat("a", 306), Coding7Bit, 2)
+	checkSplit(t, strings.Repeat("a", 307), Coding7Bit, 3)
+	checkSplit(t, strings.Repeat("€", 80), Coding7Bit, 1)
+	checkSplit(t, strings.Repeat("a", 152)+"€"+strings.Repeat("a", 10), Coding7Bit, 2)
+	checkSplit(t, strings.Repeat("ą", 70), CodingUCS2, 1)
+	checkSplit(t, strings.Repeat("ą", 71), CodingUCS2, 2)
+	checkSplit(t, strings.Repeat("😀", 70), CodingUCS2, 3)
+}
+
+func TestSplitData(t *testing.T) {
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	tmpl := Submit{UDH: UDH{PortsIE(5000, 5000)}}
+	msgs, err := SplitData(tmpl, data, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var joined []byte
+	for _, m := range msgs {
+		b, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > 13+140 {
+			t.Fatalf("TPDU too long: %d", len(b))
+		}
+		var d Submit
+		if err = d.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		joined = append(joined, d.Data...)
+	}
+	if len(msgs) != 3 || !bytes.Equal(joined, data) {
+		t.Errorf("bad split: %d parts", len(msgs))
+	}
+}
+
+func TestValidity(t *testing.T) {
+	for vp := 0; vp < 256; vp++ {
+		if v := RelativeValidity(ValidityPeriod(byte(vp))); v != byte(vp) {
+			t.Errorf("%d -> %s -> %d", vp, ValidityPeriod(byte(vp)), v)
+		}
+	}
+	if v := RelativeValidity(61 * time.Minute); v != 12 {
+		t.Errorf("61m -> %d", v)
+	}
+}
+
+func TestSeptetLen(t *testing.T) {
+	cases := []struct {
+		text string
+		n    int
+	}{
+		{"abc", 3},
+		{"é£ü Ñ", 5},
+		{"[{~}]", 10},
+		{"€5", 3},
+		{"ą", -1},
+		{"\"quoted\"", 8},
+		{"“quoted”", -1},
+	}
+	for _, c := range cases {
+		if n := SeptetLen(c.text); n != c.n {
+			t.Errorf("%q: %d != %d", c.text, n, c.n)
+		}
+		if IsGSM7(c.text) != (c.n >= 0) {
+			t.Errorf("%q: bad IsGSM7", c.text)
+		}
+	}
+}
+
+func TestNationalTables(t *testing.T) {
+	for l, tab := range lockingShift {
+		if len(tab) != 128 || tab[esc] != esc {
+			t.Errorf("%s: bad locking shift table", l)
+		}
+	}
+	// Turkish text: locking shift table only
+	text := strings.Repeat("Ağır işçi ", 16)
+	msgs, err := SplitText(Submit{}, text, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 || msgs[0].Coding != CodingUCS2 {
+		t.Fatalf("default: %d parts, %s", len(msgs), msgs[0].Coding)
+	}
+	msgs, err = SplitText(Submit{}, text, 1, LangTurkish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].Coding != Coding7Bit {
+		t.Fatalf("turkish: %d parts, %s", len(msgs), msgs[0].Coding)
+	}
+	joined := ""
+	for _, m := range msgs {
+		b, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d Submit
+		if err = d.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if ls, ss := d.UDH.Shift(); ls != LangTurkish || ss != LangDefault {
+			t.Errorf("bad shift: %s %s", ls, ss)
+		}
+		joined += d.Text
+	}
+	if joined != text {
+		t.Errorf("\nexp: %q\ngot: %q", text, joined)
+	}
+	// Spanish text: single shift table
+	text = strings.Repeat("Canción para mañana: ¿qué? ", 3)
+	msgs, err = SplitText(Submit{}, text, 1, LangSpanish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Coding != Coding7Bit {
+		t.Fatalf("spanish: %d parts, %s", len(msgs), msgs[0].Coding)
+	}
+	b, err := msgs[0].MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Submit
+	if err = d.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if ls, ss := d.UDH.Shift(); ls != LangDefault || ss != LangSpanish {
+		t.Errorf("bad shift: %s %s", ls, ss)
+	}
+	if d.Text != text {
+		t.Errorf("\nexp: %q\ngot: %q", text, d.Text)
+	}
+	// Tables are used only if they give fewer parts
+	msgs, err = SplitText(Submit{}, "ó", 1, LangSpanish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgs[0].Coding != CodingUCS2 || len(msgs[0].UDH) != 0 {
+		t.Errorf("unnecessary shift table: %+v", msgs[0])
+	}
+}
+
+func TestCountSegments(t *testing.T) {
+	cases := []struct {
+		text string
+		s    Segments
+	}{
+		{"", Segments{Coding7Bit, 0, 0, 1, 0, 160}},
+		{strings.Repeat("a", 160), Segments{Coding7Bit, 0, 0, 1, 160, 0}},
+		{strings.Repeat("a", 161), Segments{Coding7Bit, 0, 0, 2, 8, 145}},
+		{"[€]", Segments{Coding7Bit, 0, 0, 1, 6, 154}},
+		{"zażółć", Segments{CodingUCS2, 0, 0, 1, 6, 64}},
+		{strings.Repeat("ą", 71), Segments{CodingUCS2, 0, 0, 2, 4, 63}},
+		{"😀", Segments{CodingUCS2, 0, 0, 1, 2, 68}},
+	}
+	for _, c := range cases {
+		if s := CountSegments(c.text); s != c.s {
+			t.Errorf("%q:\nexp: %+v\ngot: %+v", c.text, c.s, s)
+		}
+	}
+	text := strings.Repeat("Ağır işçi ", 16)
+	s := CountSegments(text, LangTurkish)
+	exp := Segments{Coding7Bit, LangTurkish, 0, 2, 11, 138}
+	if s != exp {
+		t.Errorf("turkish:\nexp: %+v\ngot: %+v", exp, s)
+	}
+	msgs, err := SplitText(Submit{}, text, 1, LangTurkish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != s.Parts {
+		t.Errorf("SplitText: %d parts", len(msgs))
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	text, replaced := Transliterate("Zażółć „gęślą” jaźń – 5€…")
+	if text != "Zazolc \"gesla\" jazn - 5€..." {
+		t.Errorf("bad text: %q", text)
+	}
+	if string(replaced) != "żółć„ęśą”źń–…" {
+		t.Errorf("bad replaced: %q", string(replaced))
+	}
+	text, replaced = Transliterate("ą 日本")
+	if text != "ą 日本" || replaced != nil {
+		t.Errorf("partial transliteration: %q %q", text, string(replaced))
+	}
+}
@@ -0,0 +1,199 @@
+package pdu
+
+import "unicode/utf16"
+
+// capacity returns number of septets (7-bit coding) or octets (other
+// codings) available for text/data in message with header of hlen octets.
+func capacity(c Coding, hlen int) int {
+	if c == Coding7Bit {
+		return maxSeptets - septetOffset(hlen)/7
+	}
+	return maxOctets - hlen
+}
+
+// concatHeaderLen returns length of h with additional concatenation IE.
+func concatHeaderLen(h UDH) int {
+	if len(h) == 0 {
+		return 6
+	}
+	return h.Len() + 5
+}
+
+// runeLen returns number of septets (7-bit coding, cs tables) or octets
+// (UCS-2) used to encode r.
+func runeLen(c Coding, cs *charset, r rune) int {
+	if c == Coding7Bit {
+		return cs.runeLen(r)
+	}
+	return 2 * len(utf16.Encode([]rune{r}))
+}
+
+// splitRunes splits text into parts that fit into messages.
+func splitRunes(c Coding, cs *charset, h UDH, text string) []string {
+	runes := []rune(text)
+	n := 0
+	for _, r := range runes {
+		n += runeLen(c, cs, r)
+	}
+	if n <= capacity(c, h.Len()) {
+		return []string{text}
+	}
+	max := capacity(c, concatHeaderLen(h))
+	var parts []string
+	start, n := 0, 0
+	for i, r := range runes {
+		l := runeLen(c, cs, r)
+		if n+l > max {
+			parts = append(parts, string(runes[start:i]))
+			start, n = i, 0
+		}
+		n += l
+	}
+	return append(parts, string(runes[start:]))
+}
+
+// split chooses coding and national language tables that allow to send text
+// in the smallest number of messages and splits text into parts. It returns
+// h with added national language IEs and charset (7-bit coding).
+func split(h UDH, text string, locking, single []Language) (Coding, UDH, *charset, []string) {
+	c, cs := Coding7Bit, defaultCharset
+	if !IsGSM7(text) {
+		c = CodingUCS2
+	}
+	parts := splitRunes(c, cs, h, text)
+	bh, septets := h, 0
+	for _, ls := range append([]Language{LangDefault}, locking...) {
+		if ls != LangDefault && !HasLockingShift(ls) {
+			continue
+		}
+		for _, ss := range append([]Language{LangDefault}, single...) {
+			if ss != LangDefault && !HasSingleShift(ss) ||
+				ls == LangDefault && ss == LangDefault {
+				continue
+			}
+			ncs := newCharset(ls, ss)
+			n := ncs.septetLen(text)
+			if n < 0 {
+				continue
+			}
+			nh := h[:len(h):len(h)]
+			if ls != LangDefault {
+				nh = append(nh, LockingShiftIE(ls))
+			}
+			if ss != LangDefault {
+				nh = append(nh, SingleShiftIE(ss))
+			}
+			// Prefer fewer parts, then fewer septets
+			p := splitRunes(Coding7Bit, ncs, nh, text)
+			if len(p) < len(parts) ||
+				c == Coding7Bit && len(p) == len(parts) && n < septets {
+				c, bh, cs, parts, septets = Coding7Bit, nh, ncs, p, n
+			}
+		}
+	}
+	return c, bh, cs, parts
+}
+
+func addConcat(tmpl Submit, ref byte, parts, part int) Submit {
+	h := make(UDH, 0, len(tmpl.UDH)+1)
+	h = append(h, ConcatIE(ref, parts, part))
+	tmpl.UDH = append(h, tmpl.UDH...)
+	return tmpl
+}
+
+// SplitText returns messages needed to send text. Every message is a copy
+// of tmpl with Coding, Text and UDH set. Text is encoded using 7-bit coding
+// if it contains only characters from GSM 03.38 alphabet or UCS-2 otherwise.
+// National language tables for langs are used (and selected in UDH) if they
+// allow to send text in fewer messages. If text doesn't fit in one message it
+// is sent as concatenated message with ref reference number.
+func SplitText(tmpl Submit, text string, ref byte, langs ...Language) ([]Submit, error) {
+	return SplitTextTables(tmpl, text, ref, langs, langs)
+}
+
+// SplitTextTables works like SplitText but allows to specify separately
+// languages of locking shift and single shift tables that can be used.
+func SplitTextTables(tmpl Submit, text string, ref byte, locking, single []Language) ([]Submit, error) {
+	tmpl.UDH = tmpl.UDH.without(
+		IEConcat8, IEConcat16, IELockingShift, IESingleShift,
+	)
+	tmpl.Data = nil
+	var parts []string
+	tmpl.Coding, tmpl.UDH, _, parts = split(tmpl.UDH, text, locking, single)
+	if len(parts) > 255 {
+		return nil, ErrTooLong
+	}
+	if len(parts) == 1 {
+		tmpl.Text = text
+		return []Submit{tmpl}, nil
+	}
+	msgs := make([]Submit, len(parts))
+	for i, p := range parts {
+		msgs[i] = addConcat(tmpl, ref, len(parts), i+1)
+		msgs[i].Text = p
+	}
+	return msgs, nil
+}
+
+// SplitData works like SplitText but for binary data (8-bit coding).
+func SplitData(tmpl Submit, data []byte, ref byte) ([]Submit, error) {
+	tmpl.UDH = tmpl.UDH.without(IEConcat8, IEConcat16)
+	tmpl.Text = ""
+	tmpl.Coding = Coding8Bit
+	if len(data) <= capacity(Coding8Bit, tmpl.UDH.Len()) {
+		tmpl.Data = data
+		return []Submit{tmpl}, nil
+	}
+	max := capacity(Coding8Bit, concatHeaderLen(tmpl.UDH))
+	n := (len(data) + max - 1) / max
+	if n > 255 {
+		return nil, ErrTooLong
+	}
+	msgs := make([]Submit, n)
+	for i := range msgs {
+		end := (i + 1) * max
+		if end > len(data) {
+			end = len(data)
+		}
+		msgs[i] = addConcat(tmpl, ref, n, i+1)
+		msgs[i].Data = data[i*max : end]
+	}
+	return msgs, nil
+}
+
+// Segments describes how text will be sent. Used and Remaining are counted
+// in septets for 7-bit coding (characters from extension or single shift
+// table use two septets) or in UTF-16 code units for UCS-2.
+type Segments struct {
+	Coding    Coding
+	Locking   Language // National language locking shift table
+	Single    Language // National language single shift table
+	Parts     int      // Number of messages (can be greater than 255)
+	Used      int      // Characters used in the last part
+	Remaining int      // Characters that can be added to the last part
+}
+
+// CountSegments returns Segments for text sent using SplitText with langs.
+func CountSegments(text string, langs ...Language) Segments {
+	return CountSegmentsTables(text, langs, langs)
+}
+
+// CountSegmentsTables returns Segments for text sent using SplitTextTables.
+func CountSegmentsTables(text string, locking, single []Language) Segments {
+	c, h, cs, parts := split(nil, text, locking, single)
+	s := Segments{Coding: c, Parts: len(parts)}
+	s.Locking, s.Single = h.Shift()
+	hlen := h.Len()
+	if len(parts) > 1 {
+		hlen = concatHeaderLen(h)
+	}
+	for _, r := range parts[len(parts)-1] {
+		s.Used += runeLen(c, cs, r)
+	}
+	s.Remaining = capacity(c, hlen) - s.Used
+	if c == CodingUCS2 {
+		s.Used /= 2
+		s.Remaining /= 2
+	}
+	return s
+}
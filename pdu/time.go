@@ -0,0 +1,77 @@
+package pdu
+
+import "time"
+
+func bcd(b byte) int {
+	return int(b&0x0f)*10 + int(b>>4)
+}
+
+func toBCD(v int) byte {
+	return byte(v/10%10) | byte(v%10)<<4
+}
+
+// decodeTime decodes TP-SCTS or TP-DT (GSM 03.40, 9.2.3.11).
+func decodeTime(b []byte) (time.Time, error) {
+	if len(b) < 7 {
+		return time.Time{}, ErrShort
+	}
+	tz := int(b[6]&0x07)*10 + int(b[6]>>4)
+	if b[6]&0x08 != 0 {
+		tz = -tz
+	}
+	year := 2000 + bcd(b[0])
+	if year >= 2070 {
+		year -= 100
+	}
+	return time.Date(
+		year, time.Month(bcd(b[1])), bcd(b[2]),
+		bcd(b[3]), bcd(b[4]), bcd(b[5]), 0,
+		time.FixedZone("", tz*15*60),
+	), nil
+}
+
+func encodeTime(t time.Time) []byte {
+	_, off := t.Zone()
+	tz := off / (15 * 60)
+	sign := byte(0)
+	if tz < 0 {
+		sign = 0x08
+		tz = -tz
+	}
+	return []byte{
+		toBCD(t.Year() % 100), toBCD(int(t.Month())), toBCD(t.Day()),
+		toBCD(t.Hour()), toBCD(t.Minute()), toBCD(t.Second()),
+		toBCD(tz) | sign,
+	}
+}
+
+// RelativeValidity returns TP-VP in relative format (GSM 03.40, 9.2.3.12.1)
+// for the shortest period not less than d.
+func RelativeValidity(d time.Duration) byte {
+	switch {
+	case d <= 5*time.Minute:
+		return 0
+	case d <= 12*time.Hour:
+		return byte((d+5*time.Minute-1)/(5*time.Minute)) - 1
+	case d <= 24*time.Hour:
+		return byte((d-12*time.Hour+30*time.Minute-1)/(30*time.Minute)) + 143
+	case d <= 30*24*time.Hour:
+		return byte((d+24*time.Hour-1)/(24*time.Hour)) + 166
+	case d <= 63*7*24*time.Hour:
+		return byte((d+7*24*time.Hour-1)/(7*24*time.Hour)) + 192
+	}
+	return 255
+}
+
+// ValidityPeriod returns period specified by TP-VP in relative format.
+func ValidityPeriod(vp byte) time.Duration {
+	switch {
+	case vp <= 143:
+		return time.Duration(vp+1) * 5 * time.Minute
+	case vp <= 167:
+		return 12*time.Hour + time.Duration(vp-143)*30*time.Minute
+	case vp <= 196:
+		return time.Duration(vp-166) * 24 * time.Hour
+	}
+	return time.Duration(vp-192) * 7 * 24 * time.Hour
+}
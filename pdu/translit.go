@@ -0,0 +1,69 @@
+package pdu
+
+// Replacements of characters outside GSM 03.38 alphabet
+var translit = map[rune]string{
+	// Polish
+	'ą': "a", 'ć': "c", 'ę': "e", 'ł': "l", 'ń': "n", 'ó': "o", 'ś': "s",
+	'ź': "z", 'ż': "z",
+	'Ą': "A", 'Ć': "C", 'Ę': "E", 'Ł': "L", 'Ń': "N", 'Ó': "O", 'Ś': "S",
+	'Ź': "Z", 'Ż': "Z",
+	// Other Latin letters with diacritics
+	'á': "a", 'â': "a", 'ã': "a", 'ă': "a", 'ā': "a",
+	'Á': "A", 'Â': "A", 'Ã': "A", 'Ă': "A", 'Ā': "A", 'À': "A",
+	'ç': "c", 'č': "c", 'Č': "C", 'ď': "d", 'Ď': "D", 'đ': "d", 'Đ': "D",
+	'ê': "e", 'ë': "e", 'ě': "e", 'ē': "e", 'ė': "e",
+	'Ê': "E", 'Ë': "E", 'Ě': "E", 'Ē': "E", 'Ė': "E", 'È': "E",
+	'ğ': "g", 'Ğ': "G",
+	'í': "i", 'î': "i", 'ï': "i", 'ı': "i", 'ī': "i",
+	'Í': "I", 'Î': "I", 'Ï': "I", 'İ': "I", 'Ī': "I", 'Ì': "I",
+	'ľ': "l", 'ĺ': "l", 'Ľ': "L", 'Ĺ': "L", 'ň': "n", 'Ň': "N",
+	'ô': "o", 'õ': "o", 'ő': "o", 'ō': "o",
+	'Ô': "O", 'Õ': "O", 'Ő': "O", 'Ō': "O", 'Ò': "O",
+	'ř': "r", 'Ř': "R", 'ŕ': "r", 'Ŕ': "R",
+	'š': "s", 'ş': "s", 'ș': "s", 'Š': "S", 'Ş': "S", 'Ș': "S",
+	'ť': "t", 'ţ': "t", 'ț': "t", 'Ť': "T", 'Ţ': "T", 'Ț': "T",
+	'ú': "u", 'û': "u", 'ů': "u", 'ű': "u", 'ū': "u",
+	'Ú': "U", 'Û': "U", 'Ů': "U", 'Ű': "U", 'Ū': "U", 'Ù': "U",
+	'ý': "y", 'ÿ': "y", 'Ý': "Y", 'Ÿ': "Y",
+	'ž': "z", 'Ž': "Z",
+	// Typographic characters
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '′': "'",
+	'“': "\"", '”': "\"", '„': "\"", '‟': "\"", '″': "\"",
+	'«': "\"", '»': "\"", '‹': "'", '›': "'",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-", '−': "-",
+	'…': "...", '•': "*", '·': ".",
+	'\u00a0': " ", '\u2002': " ", '\u2009': " ", '\u202f': " ",
+	'\t': " ",
+}
+
+// Transliterate replaces characters from outside of GSM 03.38 alphabet
+// (diacritics, typographic quotes, dashes, ...) with similar characters from
+// this alphabet. It returns replaced characters (every character once).
+// If text can't be fully transliterated it is returned unchanged (it will be
+// sent using UCS-2 anyway).
+func Transliterate(text string) (string, []rune) {
+	if IsGSM7(text) {
+		return text, nil
+	}
+	var (
+		out      []rune
+		replaced []rune
+		seen     = make(map[rune]bool)
+	)
+	for _, r := range text {
+		if GSM7Len(r) != 0 {
+			out = append(out, r)
+			continue
+		}
+		s, ok := translit[r]
+		if !ok {
+			return text, nil
+		}
+		out = append(out, []rune(s)...)
+		if !seen[r] {
+			seen[r] = true
+			replaced = append(replaced, r)
+		}
+	}
+	return string(out), replaced
+}
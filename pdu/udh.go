@@ -0,0 +1,134 @@
+package pdu
+
+// Information element identifiers
+const (
+	IEConcat8      = 0x00 // Concatenated message, 8-bit reference
+	IEPorts8       = 0x04 // Application port addressing, 8-bit
+	IEPorts16      = 0x05 // Application port addressing, 16-bit
+	IEConcat16     = 0x08 // Concatenated message, 16-bit reference
+	IESingleShift  = 0x24 // National language single shift
+	IELockingShift = 0x25 // National language locking shift
+)
+
+// IE is information element of User Data Header
+type IE struct {
+	ID   byte
+	Data []byte
+}
+
+// UDH is User Data Header
+type UDH []IE
+
+// Len returns length of encoded header in octets (including UDHL octet).
+func (h UDH) Len() int {
+	if len(h) == 0 {
+		return 0
+	}
+	n := 1
+	for _, ie := range h {
+		n += 2 + len(ie.Data)
+	}
+	return n
+}
+
+// Encode returns encoded header (including UDHL octet) or nil if h is
+// empty.
+func (h UDH) Encode() []byte {
+	if len(h) == 0 {
+		return nil
+	}
+	b := make([]byte, 1, h.Len())
+	b[0] = byte(h.Len() - 1)
+	for _, ie := range h {
+		b = append(b, ie.ID, byte(len(ie.Data)))
+		b = append(b, ie.Data...)
+	}
+	return b
+}
+
+// DecodeUDH decodes header from b (b[0] is UDHL). It returns number of octets
+// used.
+func DecodeUDH(b []byte) (UDH, int, error) {
+	if len(b) == 0 || len(b) < 1+int(b[0]) {
+		return nil, 0, ErrShort
+	}
+	n := 1 + int(b[0])
+	var h UDH
+	for i := 1; i < n; {
+		if i+2 > n || i+2+int(b[i+1]) > n {
+			return nil, 0, ErrShort
+		}
+		l := int(b[i+1])
+		h = append(h, IE{b[i], append([]byte(nil), b[i+2:i+2+l]...)})
+		i += 2 + l
+	}
+	return h, n, nil
+}
+
+// Get returns first element with specified id.
+func (h UDH) Get(id byte) (IE, bool) {
+	for _, ie := range h {
+		if ie.ID == id {
+			return ie, true
+		}
+	}
+	return IE{}, false
+}
+
+// ConcatIE returns concatenation element with 8-bit reference number.
+func ConcatIE(ref byte, parts, part int) IE {
+	return IE{IEConcat8, []byte{ref, byte(parts), byte(part)}}
+}
+
+// Concat returns reference number, number of parts and part number (from 1)
+// of concatenated message.
+func (h UDH) Concat() (ref, parts, part int, ok bool) {
+	for _, ie := range h {
+		switch {
+		case ie.ID == IEConcat8 && len(ie.Data) == 3:
+			d := ie.Data
+			return int(d[0]), int(d[1]), int(d[2]), true
+		case ie.ID == IEConcat16 && len(ie.Data) == 4:
+			d := ie.Data
+			return int(d[0])<<8 | int(d[1]), int(d[2]), int(d[3]), true
+		}
+	}
+	return
+}
+
+// PortsIE returns 16-bit application port addressing element.
+func PortsIE(dst, src int) IE {
+	return IE{
+		IEPorts16,
+		[]byte{byte(dst >> 8), byte(dst), byte(src >> 8), byte(src)},
+	}
+}
+
+// Ports returns application destination and source ports.
+func (h UDH) Ports() (dst, src int, ok bool) {
+	for _, ie := range h {
+		switch {
+		case ie.ID == IEPorts8 && len(ie.Data) == 2:
+			return int(ie.Data[0]), int(ie.Data[1]), true
+		case ie.ID == IEPorts16 && len(ie.Data) == 4:
+			d := ie.Data
+			return int(d[0])<<8 | int(d[1]), int(d[2])<<8 | int(d[3]), true
+		}
+	}
+	return
+}
+
+// without returns copy of h without elements with specified ids.
+func (h UDH) without(ids ...byte) UDH {
+	var r UDH
+loop:
+	for _, ie := range h {
+		for _, id := range ids {
+			if ie.ID == id {
+				continue loop
+			}
+		}
+		r = append(r, ie)
+	}
+	return r
+}
@@ -0,0 +1,105 @@
+package pdu
+
+import "unicode/utf16"
+
+const (
+	maxSeptets = 160 // TP-UD capacity in septets
+	maxOctets  = 140 // TP-UD capacity in octets
+)
+
+// septetOffset returns bit offset of first septet of text after header of
+// hlen octets (GSM 03.40, 9.2.3.24).
+func septetOffset(hlen int) int {
+	return (hlen*8 + 6) / 7 * 7
+}
+
+func encodeUCS2(text string) []byte {
+	u := utf16.Encode([]rune(text))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		b[2*i] = byte(c >> 8)
+		b[2*i+1] = byte(c)
+	}
+	return b
+}
+
+func decodeUCS2(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(u))
+}
+
+// encodeUD returns TP-UDL and TP-UD.
+func encodeUD(c Coding, h UDH, text string, data []byte) (byte, []byte, error) {
+	hb := h.Encode()
+	switch c {
+	case Coding7Bit:
+		s, err := charsetFor(h).encode(text)
+		if err != nil {
+			return 0, nil, err
+		}
+		off := septetOffset(len(hb))
+		udl := off/7 + len(s)
+		if udl > maxSeptets {
+			return 0, nil, ErrTooLong
+		}
+		b := pack7(s, off)
+		copy(b, hb)
+		return byte(udl), b, nil
+	case CodingUCS2:
+		data = encodeUCS2(text)
+	}
+	b := append(hb, data...)
+	if len(b) > maxOctets {
+		return 0, nil, ErrTooLong
+	}
+	return byte(len(b)), b, nil
+}
+
+// decodeUD decodes TP-UD of udl length (in septets or octets).
+func decodeUD(c Coding, udhi bool, udl int, b []byte) (h UDH, text string, data []byte, err error) {
+	if c == Coding7Bit {
+		if len(b) < (udl*7+7)/8 {
+			err = ErrShort
+			return
+		}
+		off := 0
+		if udhi {
+			var n int
+			if h, n, err = DecodeUDH(b); err != nil {
+				return
+			}
+			off = septetOffset(n)
+		}
+		if off/7 > udl {
+			err = ErrShort
+			return
+		}
+		var s []byte
+		if s, err = unpack7(b, off, udl-off/7); err != nil {
+			return
+		}
+		text = charsetFor(h).decode(s)
+		return
+	}
+	if len(b) < udl {
+		err = ErrShort
+		return
+	}
+	b = b[:udl]
+	if udhi {
+		var n int
+		if h, n, err = DecodeUDH(b); err != nil {
+			return
+		}
+		b = b[n:]
+	}
+	if c == CodingUCS2 {
+		text = decodeUCS2(b)
+	} else {
+		data = append([]byte(nil), b...)
+	}
+	return
+}
@@ -0,0 +1,381 @@
+package gammu
+
+/*
+#include <stdlib.h>
+#include <gammu.h>
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+var ErrTooManyFields = errors.New("gammu: too many fields in phonebook entry")
+
+// NumberType specifies type of phone number in phonebook entry
+type NumberType int
+
+const (
+	NumberGeneral NumberType = iota
+	NumberMobile
+	NumberHome
+	NumberWork
+	NumberFax
+	NumberPager
+	NumberOther
+)
+
+func (t NumberType) String() string {
+	switch t {
+	case NumberGeneral:
+		return "general"
+	case NumberMobile:
+		return "mobile"
+	case NumberHome:
+		return "home"
+	case NumberWork:
+		return "work"
+	case NumberFax:
+		return "fax"
+	case NumberPager:
+		return "pager"
+	case NumberOther:
+		return "other"
+	}
+	return "unknown"
+}
+
+// gammu returns libGammu entry type and location for t.
+func (t NumberType) gammu() (C.GSM_EntryType, C.GSM_EntryLocation) {
+	switch t {
+	case NumberMobile:
+		return C.PBK_Number_Mobile, C.PBK_Location_Unknown
+	case NumberHome:
+		return C.PBK_Number_General, C.PBK_Location_Home
+	case NumberWork:
+		return C.PBK_Number_General, C.PBK_Location_Work
+	case NumberFax:
+		return C.PBK_Number_Fax, C.PBK_Location_Unknown
+	case NumberPager:
+		return C.PBK_Number_Pager, C.PBK_Location_Unknown
+	case NumberOther:
+		return C.PBK_Number_Other, C.PBK_Location_Unknown
+	}
+	return C.PBK_Number_General, C.PBK_Location_Unknown
+}
+
+// Number is phone number from phonebook entry
+type Number struct {
+	Type   NumberType
+	Number string
+}
+
+// PhonebookEntry is entry of phonebook stored in SIM or phone memory. SIM
+// entries usually contain only Name and one number. Other fields of entries
+// stored in phone (dates, pictures, addresses, ...) aren't supported and
+// are lost when entry is changed using SetMemory.
+type PhonebookEntry struct {
+	Memory   MemoryType
+	Location int
+
+	Name      string // Full name
+	FirstName string
+	LastName  string
+	Numbers   []Number
+	Emails    []string
+	Note      string
+}
+
+// truncate returns prefix of s that has at most max UCS-2 characters.
+func truncate(s string, max int) string {
+	n := 0
+	for i, r := range s {
+		l := 1
+		if r >= 0x10000 {
+			l = 2 // Surrogate pair
+		}
+		if n+l > max {
+			return s[:i]
+		}
+		n += l
+	}
+	return s
+}
+
+// gammu converts e to libGammu entry. Texts longer than allowed by libGammu
+// are truncated.
+func (e *PhonebookEntry) gammu(g *C.GSM_MemoryEntry) error {
+	*g = C.GSM_MemoryEntry{}
+	g.MemoryType = C.GSM_MemoryType(e.Memory)
+	g.Location = C.int(e.Location)
+	add := func(t C.GSM_EntryType, loc C.GSM_EntryLocation, text string) error {
+		if text == "" {
+			return nil
+		}
+		if g.EntriesNum == C.GSM_PHONEBOOK_ENTRIES {
+			return ErrTooManyFields
+		}
+		s := &g.Entries[g.EntriesNum]
+		s.EntryType = t
+		s.Location = loc
+		decodeUTF8(&s.Text[0], truncate(text, C.GSM_PHONEBOOK_TEXT_LENGTH))
+		g.EntriesNum++
+		return nil
+	}
+	none := C.GSM_EntryLocation(C.PBK_Location_Unknown)
+	if err := add(C.PBK_Text_Name, none, e.Name); err != nil {
+		return err
+	}
+	if err := add(C.PBK_Text_FirstName, none, e.FirstName); err != nil {
+		return err
+	}
+	if err := add(C.PBK_Text_LastName, none, e.LastName); err != nil {
+		return err
+	}
+	for _, n := range e.Numbers {
+		t, loc := n.Type.gammu()
+		if err := add(t, loc, n.Number); err != nil {
+			return err
+		}
+	}
+	for _, m := range e.Emails {
+		if err := add(C.PBK_Text_Email, none, m); err != nil {
+			return err
+		}
+	}
+	return add(C.PBK_Text_Note, none, e.Note)
+}
+
+// decodeEntry converts libGammu entry to PhonebookEntry and frees memory
+// allocated by libGammu for g.
+func decodeEntry(g *C.GSM_MemoryEntry) (e PhonebookEntry) {
+	defer C.GSM_FreeMemoryEntry(g)
+	e.Memory = MemoryType(g.MemoryType)
+	e.Location = int(g.Location)
+	for i := 0; i < int(g.EntriesNum); i++ {
+		s := &g.Entries[i]
+		var nt NumberType
+		switch s.EntryType {
+		case C.PBK_Text_Name:
+			e.Name = encodeUTF8(&s.Text[0])
+			continue
+		case C.PBK_Text_FirstName:
+			e.FirstName = encodeUTF8(&s.Text[0])
+			continue
+		case C.PBK_Text_LastName:
+			e.LastName = encodeUTF8(&s.Text[0])
+			continue
+		case C.PBK_Text_Email, C.PBK_Text_Email2:
+			e.Emails = append(e.Emails, encodeUTF8(&s.Text[0]))
+			continue
+		case C.PBK_Text_Note:
+			if e.Note != "" {
+				e.Note += "\n"
+			}
+			e.Note += encodeUTF8(&s.Text[0])
+			continue
+		case C.PBK_Number_General:
+			switch s.Location {
+			case C.PBK_Location_Home:
+				nt = NumberHome
+			case C.PBK_Location_Work:
+				nt = NumberWork
+			default:
+				nt = NumberGeneral
+			}
+		case C.PBK_Number_Mobile:
+			nt = NumberMobile
+		case C.PBK_Number_Fax:
+			nt = NumberFax
+		case C.PBK_Number_Pager:
+			nt = NumberPager
+		case C.PBK_Number_Other, C.PBK_Number_Messaging, C.PBK_Number_Video:
+			nt = NumberOther
+		default:
+			continue
+		}
+		e.Numbers = append(e.Numbers, Number{nt, encodeUTF8(&s.Text[0])})
+	}
+	return
+}
+
+// GetMemory reads phonebook entry from specified memory (eg. MemSIM or
+// MemPhone) and location.
+func (sm *StateMachine) GetMemory(mem MemoryType, location int) (entry PhonebookEntry, err error) {
+	var g C.GSM_MemoryEntry
+	g.MemoryType = C.GSM_MemoryType(mem)
+	g.Location = C.int(location)
+	if e := C.GSM_GetMemory(sm.g, &g); e != C.ERR_NONE {
+		err = Error{"GetMemory", e}
+		return
+	}
+	return decodeEntry(&g), nil
+}
+
+// SetMemory writes e to e.Memory at e.Location (overwrites existing entry).
+func (sm *StateMachine) SetMemory(e *PhonebookEntry) error {
+	var g C.GSM_MemoryEntry
+	if err := e.gammu(&g); err != nil {
+		return err
+	}
+	if e := C.GSM_SetMemory(sm.g, &g); e != C.ERR_NONE {
+		return Error{"SetMemory", e}
+	}
+	return nil
+}
+
+// AddMemory writes e to first free location in e.Memory. It sets
+// e.Location to location used.
+func (sm *StateMachine) AddMemory(e *PhonebookEntry) error {
+	var g C.GSM_MemoryEntry
+	if err := e.gammu(&g); err != nil {
+		return err
+	}
+	if e := C.GSM_AddMemory(sm.g, &g); e != C.ERR_NONE {
+		return Error{"AddMemory", e}
+	}
+	e.Location = int(g.Location)
+	return nil
+}
+
+// DeleteMemory deletes phonebook entry from specified memory and location.
+func (sm *StateMachine) DeleteMemory(mem MemoryType, location int) error {
+	var g C.GSM_MemoryEntry
+	g.MemoryType = C.GSM_MemoryType(mem)
+	g.Location = C.int(location)
+	if e := C.GSM_DeleteMemory(sm.g, &g); e != C.ERR_NONE {
+		return Error{"DeleteMemory", e}
+	}
+	return nil
+}
+
+// GetMemoryStatus returns number of used and free locations in mem.
+func (sm *StateMachine) GetMemoryStatus(mem MemoryType) (used, free int, err error) {
+	var s C.GSM_MemoryStatus
+	s.MemoryType = C.GSM_MemoryType(mem)
+	if e := C.GSM_GetMemoryStatus(sm.g, &s); e != C.ERR_NONE {
+		err = Error{"GetMemoryStatus", e}
+		return
+	}
+	return int(s.MemoryUsed), int(s.MemoryFree), nil
+}
+
+// MemoryList iterates over phonebook entries stored in the phone.
+type MemoryList struct {
+	sm       *StateMachine
+	memory   MemoryType
+	start    bool
+	location C.int
+}
+
+// ListMemory returns iterator over phonebook entries stored in mem.
+func (sm *StateMachine) ListMemory(mem MemoryType) *MemoryList {
+	return &MemoryList{sm: sm, memory: mem, start: true}
+}
+
+// Next returns next entry from the list.
+// Returns io.EOF if there is no more entries to read
+func (l *MemoryList) Next() (PhonebookEntry, error) {
+	return l.NextContext(context.Background())
+}
+
+// NextContext works like Next but returns ctx.Err() without reading anything
+// if ctx is done.
+func (l *MemoryList) NextContext(ctx context.Context) (entry PhonebookEntry, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	var g C.GSM_MemoryEntry
+	g.MemoryType = C.GSM_MemoryType(l.memory)
+	g.Location = l.location
+	start := C.gboolean(C.FALSE)
+	if l.start {
+		start = C.TRUE
+	}
+	if e := C.GSM_GetNextMemory(l.sm.g, &g, start); e != C.ERR_NONE {
+		if e == C.ERR_EMPTY {
+			err = io.EOF
+		} else {
+			err = Error{"GetNextMemory", e}
+		}
+		return
+	}
+	l.start = false
+	l.location = g.Location
+	return decodeEntry(&g), nil
+}
+
+// OwnNumbers returns phone numbers (MSISDN) stored in SIM own numbers memory
+// (MemOwnNumbers). Many operators don't store them on SIM, so the returned
+// list can be empty.
+func (sm *StateMachine) OwnNumbers() ([]string, error) {
+	var nums []string
+	l := sm.ListMemory(MemOwnNumbers)
+	for {
+		e, err := l.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nums, nil
+			}
+			return nil, err
+		}
+		for _, n := range e.Numbers {
+			nums = append(nums, n.Number)
+		}
+	}
+}
+
+const vCardBufLen = 16 * 1024
+
+// VCard returns e encoded as vCard 2.1.
+func (e *PhonebookEntry) VCard() ([]byte, error) {
+	var g C.GSM_MemoryEntry
+	if err := e.gammu(&g); err != nil {
+		return nil, err
+	}
+	buf := (*C.char)(C.malloc(vCardBufLen))
+	defer C.free(unsafe.Pointer(buf))
+	var n C.size_t
+	if e := C.GSM_EncodeVCARD(
+		nil, buf, vCardBufLen, &n, &g, C.TRUE, C.SonyEricsson_VCard21,
+	); e != C.ERR_NONE {
+		return nil, Error{"EncodeVCARD", e}
+	}
+	return C.GoBytes(unsafe.Pointer(buf), C.int(n)), nil
+}
+
+// ParseVCards decodes all vCards from b (eg. exported phonebook). Memory and
+// Location of returned entries are zero.
+func ParseVCards(b []byte) ([]PhonebookEntry, error) {
+	s := string(b)
+	if strings.IndexByte(s, 0) != -1 {
+		return nil, Error{"DecodeVCARD", C.ERR_INVALIDDATA}
+	}
+	buf := C.CString(s)
+	defer C.free(unsafe.Pointer(buf))
+	var (
+		entries []PhonebookEntry
+		pos     C.size_t
+	)
+	for int(pos) < len(s) {
+		var g C.GSM_MemoryEntry
+		prev := pos
+		e := C.GSM_DecodeVCARD(nil, buf, &pos, &g, C.SonyEricsson_VCard21)
+		if e == C.ERR_EMPTY {
+			break
+		}
+		if e != C.ERR_NONE {
+			return entries, Error{"DecodeVCARD", e}
+		}
+		ent := decodeEntry(&g)
+		ent.Memory, ent.Location = 0, 0
+		entries = append(entries, ent)
+		if pos <= prev {
+			break
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,527 @@
+package gammu
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+var ErrClosed = errors.New("gammu: SafeStateMachine is closed")
+
+// SafeStateMachine wraps StateMachine so it can be shared by many goroutines.
+// libGammu isn't reentrant, so all calls are serialized and run on a
+// dedicated goroutine locked to one OS thread. Handlers set by SetIncomingCall
+// and SetIncomingUSSD run on this goroutine too, so they can't call
+// SafeStateMachine methods.
+type SafeStateMachine struct {
+	sm        *StateMachine
+	reqs      chan func()
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSafeStateMachine starts the goroutine that serves calls to sm. sm
+// shouldn't be used directly after this (use Do instead).
+func NewSafeStateMachine(sm *StateMachine) *SafeStateMachine {
+	s := &SafeStateMachine{
+		sm:     sm,
+		reqs:   make(chan func()),
+		closed: make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *SafeStateMachine) loop() {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	for {
+		select {
+		case f := <-s.reqs:
+			f()
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// Close stops the goroutine that serves calls (after the current call
+// returns). It doesn't disconnect the phone. Calls made after Close return
+// ErrClosed (or zero values if method doesn't return error). Close can be
+// called many times.
+func (s *SafeStateMachine) Close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+}
+
+// Do calls f with wrapped StateMachine and waits until it returns. Use it for
+// things that don't have wrapper methods (eg. SMSAssembler.Read or setting
+// Timeout). f must not use sm after return. It returns ErrClosed without
+// calling f after Close.
+func (s *SafeStateMachine) Do(f func(sm *StateMachine)) error {
+	return s.DoContext(context.Background(), f)
+}
+
+// DoContext works like Do but gives up waiting for its turn when ctx is done.
+// It returns ctx.Err() in this case. Running f isn't interrupted.
+func (s *SafeStateMachine) DoContext(ctx context.Context, f func(sm *StateMachine)) error {
+	done := make(chan struct{})
+	select {
+	case s.reqs <- func() { f(s.sm); close(done) }:
+	case <-s.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-done
+	return nil
+}
+
+func (s *SafeStateMachine) Connect() (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.Connect()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) ConnectContext(ctx context.Context) (err error) {
+	if e := s.DoContext(ctx, func(sm *StateMachine) {
+		err = sm.ConnectContext(ctx)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) IsConnected() (ok bool) {
+	s.Do(func(sm *StateMachine) { ok = sm.IsConnected() })
+	return
+}
+
+func (s *SafeStateMachine) Disconnect() (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.Disconnect()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) Reset() (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.Reset()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) HardReset() (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.HardReset()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) ReadDevice() (n int) {
+	s.Do(func(sm *StateMachine) { n = sm.ReadDevice() })
+	return
+}
+
+// SMS
+
+func (s *SafeStateMachine) SendSMS(number, text string, report bool) (res SendResult, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		res, err = sm.SendSMS(number, text, report)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SendSMSContext(ctx context.Context, number, text string, opts *SendOptions) (res SendResult, err error) {
+	if e := s.DoContext(ctx, func(sm *StateMachine) {
+		res, err = sm.SendSMSContext(ctx, number, text, opts)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SendLongSMS(number, text string, report bool) (res []SendResult, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		res, err = sm.SendLongSMS(number, text, report)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SendLongSMSContext(ctx context.Context, number, text string, opts *SendOptions) (res []SendResult, err error) {
+	if e := s.DoContext(ctx, func(sm *StateMachine) {
+		res, err = sm.SendLongSMSContext(ctx, number, text, opts)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SendDataSMS(number string, dstPort, srcPort int, data []byte, report bool) (res []SendResult, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		res, err = sm.SendDataSMS(number, dstPort, srcPort, data, report)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SendDataSMSContext(ctx context.Context, number string, dstPort, srcPort int, data []byte, opts *SendOptions) (res []SendResult, err error) {
+	if e := s.DoContext(ctx, func(sm *StateMachine) {
+		res, err = sm.SendDataSMSContext(ctx, number, dstPort, srcPort, data, opts)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) GetSMS() (sms SMS, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		sms, err = sm.GetSMS()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) GetSMSContext(ctx context.Context) (sms SMS, err error) {
+	if e := s.DoContext(ctx, func(sm *StateMachine) {
+		sms, err = sm.GetSMSContext(ctx)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) DeleteSMS(location, folder int) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.DeleteSMS(location, folder)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) MoveSMS(location, folder, dstFolder int) (n int, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		n, err = sm.MoveSMS(location, folder, dstFolder)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) GetSMSFolders() (f []SMSFolder, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		f, err = sm.GetSMSFolders()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) GetSMSStatus() (status SMSMemoryStatus, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		status, err = sm.GetSMSStatus()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+// SafeSMSList is SMSList that reads messages using SafeStateMachine. Other
+// calls can be made between successive Next calls.
+type SafeSMSList struct {
+	s *SafeStateMachine
+	l *SMSList
+}
+
+func (s *SafeStateMachine) ListSMS(folder int) *SafeSMSList {
+	return &SafeSMSList{s, s.sm.ListSMS(folder)}
+}
+
+func (s *SafeStateMachine) ListSMSMemory(mem MemoryType) *SafeSMSList {
+	return &SafeSMSList{s, s.sm.ListSMSMemory(mem)}
+}
+
+func (l *SafeSMSList) Next() (sms SMS, err error) {
+	if e := l.s.Do(func(*StateMachine) {
+		sms, err = l.l.Next()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (l *SafeSMSList) NextContext(ctx context.Context) (sms SMS, err error) {
+	if e := l.s.DoContext(ctx, func(*StateMachine) {
+		sms, err = l.l.NextContext(ctx)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SetIncomingSMS(c chan<- IncomingSMS) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.SetIncomingSMS(c)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+// Calls and USSD
+
+func (s *SafeStateMachine) Dial(number string) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.Dial(number)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) AnswerCall(id int) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.AnswerCall(id)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) CancelCall(id int) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.CancelCall(id)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SetIncomingCall(f func(Call)) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.SetIncomingCall(f)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SetIncomingUSSD(f func(USSD)) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.SetIncomingUSSD(f)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) DialService(code string) (u USSD, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		u, err = sm.DialService(code)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) DialServiceContext(ctx context.Context, code string) (u USSD, err error) {
+	if e := s.DoContext(ctx, func(sm *StateMachine) {
+		u, err = sm.DialServiceContext(ctx, code)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+// Phonebook
+
+func (s *SafeStateMachine) GetMemory(mem MemoryType, location int) (entry PhonebookEntry, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		entry, err = sm.GetMemory(mem, location)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SetMemory(entry *PhonebookEntry) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.SetMemory(entry)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) AddMemory(entry *PhonebookEntry) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.AddMemory(entry)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) DeleteMemory(mem MemoryType, location int) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.DeleteMemory(mem, location)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) GetMemoryStatus(mem MemoryType) (used, free int, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		used, free, err = sm.GetMemoryStatus(mem)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) OwnNumbers() (nums []string, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		nums, err = sm.OwnNumbers()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+// SafeMemoryList is MemoryList that reads entries using SafeStateMachine.
+// Other calls can be made between successive Next calls.
+type SafeMemoryList struct {
+	s *SafeStateMachine
+	l *MemoryList
+}
+
+func (s *SafeStateMachine) ListMemory(mem MemoryType) *SafeMemoryList {
+	return &SafeMemoryList{s, s.sm.ListMemory(mem)}
+}
+
+func (l *SafeMemoryList) Next() (entry PhonebookEntry, err error) {
+	if e := l.s.Do(func(*StateMachine) {
+		entry, err = l.l.Next()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (l *SafeMemoryList) NextContext(ctx context.Context) (entry PhonebookEntry, err error) {
+	if e := l.s.DoContext(ctx, func(*StateMachine) {
+		entry, err = l.l.NextContext(ctx)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+// Device and network
+
+func (s *SafeStateMachine) Manufacturer() (m string, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		m, err = sm.Manufacturer()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) Model() (m string, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		m, err = sm.Model()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) Firmware() (version, date string, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		version, date, err = sm.Firmware()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) IMEI() (imei string, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		imei, err = sm.IMEI()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SIMIMSI() (imsi string, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		imsi, err = sm.SIMIMSI()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SecurityStatus() (c SecurityCode, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		c, err = sm.SecurityStatus()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) EnterSecurityCode(t SecurityCode, code string) (err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		err = sm.EnterSecurityCode(t, code)
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) SignalQuality() (sq SignalQuality, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		sq, err = sm.SignalQuality()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) BatteryCharge() (bc BatteryCharge, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		bc, err = sm.BatteryCharge()
+	}); e != nil {
+		err = e
+	}
+	return
+}
+
+func (s *SafeStateMachine) NetworkInfo() (ni NetworkInfo, err error) {
+	if e := s.Do(func(sm *StateMachine) {
+		ni, err = sm.NetworkInfo()
+	}); e != nil {
+		err = e
+	}
+	return
+}
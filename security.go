@@ -0,0 +1,72 @@
+package gammu
+
+/*
+#include <stdlib.h>
+#include <string.h>
+#include <gammu.h>
+*/
+import "C"
+import "unsafe"
+
+// SecurityCode describes type of security code
+type SecurityCode int
+
+const (
+	SecNone    SecurityCode = C.SEC_None         // No code required
+	SecCode    SecurityCode = C.SEC_SecurityCode // Phone security code
+	SecPIN     SecurityCode = C.SEC_Pin
+	SecPIN2    SecurityCode = C.SEC_Pin2
+	SecPUK     SecurityCode = C.SEC_Puk
+	SecPUK2    SecurityCode = C.SEC_Puk2
+	SecPhone   SecurityCode = C.SEC_Phone   // Phone to SIM lock code
+	SecNetwork SecurityCode = C.SEC_Network // Network personalisation code
+)
+
+func (c SecurityCode) String() string {
+	switch c {
+	case SecNone:
+		return "none"
+	case SecCode:
+		return "security code"
+	case SecPIN:
+		return "PIN"
+	case SecPIN2:
+		return "PIN2"
+	case SecPUK:
+		return "PUK"
+	case SecPUK2:
+		return "PUK2"
+	case SecPhone:
+		return "phone code"
+	case SecNetwork:
+		return "network code"
+	}
+	return "unknown"
+}
+
+// SecurityStatus returns type of security code that phone waits for or
+// SecNone if no code is required.
+func (sm *StateMachine) SecurityStatus() (SecurityCode, error) {
+	var t C.GSM_SecurityCodeType
+	if e := C.GSM_GetSecurityStatus(sm.g, &t); e != C.ERR_NONE {
+		return 0, Error{"GetSecurityStatus", e}
+	}
+	return SecurityCode(t), nil
+}
+
+// EnterSecurityCode enters code of type t (see SecurityStatus). Every wrong
+// PIN decreases the number of remaining attempts, so don't retry it blindly.
+func (sm *StateMachine) EnterSecurityCode(t SecurityCode, code string) error {
+	var sc C.GSM_SecurityCode
+	if len(code) >= len(sc.Code) {
+		return Error{"EnterSecurityCode", C.ERR_MOREMEMORY}
+	}
+	sc.Type = C.GSM_SecurityCodeType(t)
+	cc := C.CString(code)
+	defer C.free(unsafe.Pointer(cc))
+	C.strcpy(&sc.Code[0], cc)
+	if e := C.GSM_EnterSecurityCode(sm.g, &sc); e != C.ERR_NONE {
+		return Error{"EnterSecurityCode", e}
+	}
+	return nil
+}
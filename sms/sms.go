@@ -5,8 +5,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"github.com/ziutek/gogammu/pdu"
 	"net"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type Sender struct {
@@ -14,6 +17,11 @@ type Sender struct {
 	Server string // IP address:port or unix domain socket path
 	Delete bool   // Will message need to be deleted after sent/reported?
 	Report bool   // Is report required?
+	Flash  bool   // Send as flash message (displayed immediately)?
+
+	// Validity period (rounded to seconds). Message isn't delivered after
+	// this period. Zero means SMSC default.
+	Validity time.Duration
 }
 
 // Sends txt as SMS to recipients. Recipient need to be specified as
@@ -64,6 +72,17 @@ func (s *Sender) Send(txt string, recipients ...string) error {
 			return err
 		}
 	}
+	if s.Flash {
+		if err = writeln(w, "flash"); err != nil {
+			return err
+		}
+	}
+	if s.Validity > 0 {
+		v := fmt.Sprintf("valid %ds", s.Validity/time.Second)
+		if err = writeln(w, v); err != nil {
+			return err
+		}
+	}
 	if err = newLine(w); err != nil {
 		return err
 	}
@@ -101,16 +120,24 @@ func writeln(w *bufio.Writer, s string) error {
 	return newLine(w)
 }
 
-// Returns number of characters that will be used to send txt via SMS
+// Count returns encoding, number of parts and number of characters used in
+// and remaining to the last part of message, for txt sent by SMSd configured
+// to use langs national language tables (see Languages in smsd.cfg).
+func Count(txt string, langs ...pdu.Language) pdu.Segments {
+	return pdu.CountSegments(strings.TrimSpace(txt), langs...)
+}
+
+// Returns number of characters that will be used to send txt via SMS.
+// Characters from GSM 03.38 extension table (eg. '[', '{', '€') use two
+// characters.
+//
+// Deprecated: Len doesn't take into account UCS-2 and concatenation, use
+// Count.
 func Len(txt string) int {
-	m, n := 1, 0
-	for _, r := range txt {
-		if r > 0x7F {
-			m = 4
-		}
-		n++
+	if n := pdu.SeptetLen(txt); n >= 0 {
+		return n
 	}
-	return m * n
+	return 4 * utf8.RuneCountInString(txt)
 }
 
 func AppendId(phones []string, id int) []string {
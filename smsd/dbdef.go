@@ -6,6 +6,8 @@ const (
 	outboxTable     = "SMSd_Outbox"
 	recipientsTable = "SMSd_Recipients"
 	inboxTable      = "SMSd_Inbox"
+	callsTable      = "SMSd_MissedCalls"
+	devicesTable    = "SMSd_Devices"
 )
 
 const createOutbox = `CREATE TABLE IF NOT EXISTS ` + outboxTable + ` (
@@ -14,6 +16,8 @@ const createOutbox = `CREATE TABLE IF NOT EXISTS ` + outboxTable + ` (
 	src    varchar(16) NOT NULL,
 	report boolean NOT NULL,
 	del    boolean NOT NULL,
+	flash  boolean NOT NULL,
+	valid  int unsigned NOT NULL,
 	body   text NOT NULL,
 	PRIMARY KEY (id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8`
@@ -24,7 +28,9 @@ const createRecipients = `CREATE TABLE IF NOT EXISTS ` + recipientsTable + ` (
 	number varchar(16) NOT NULL,
 	dstId  int unsigned NOT NULL,
 	sent   datetime NOT NULL,
+	msgRef tinyint unsigned NOT NULL,
 	report datetime NOT NULL,
+	status tinyint unsigned NOT NULL,
 	PRIMARY KEY (id),
 	FOREIGN KEY (msgId) REFERENCES ` + outboxTable + `(id) ON DELETE CASCADE,
 	KEY dstId (dstId)
@@ -37,6 +43,36 @@ const createInbox = `CREATE TABLE IF NOT EXISTS ` + inboxTable + ` (
 	srcId  int unsigned NOT NULL,
 	body   text NOT NULL,
 	note   varchar(32) NOT NULL,
+	ownNumber varchar(16) NOT NULL,
 	PRIMARY KEY (id),
 	KEY srcId (srcId)
 ) ENGINE=MyISAM DEFAULT CHARSET=utf8`
+
+const createCalls = `CREATE TABLE IF NOT EXISTS ` + callsTable + ` (
+	id     int unsigned NOT NULL AUTO_INCREMENT,
+	time   datetime NOT NULL,
+	number varchar(16) NOT NULL,
+	ownNumber varchar(16) NOT NULL,
+	PRIMARY KEY (id)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8`
+
+const createDevices = `CREATE TABLE IF NOT EXISTS ` + devicesTable + ` (
+	imei         varchar(20) NOT NULL,
+	imsi         varchar(20) NOT NULL,
+	manufacturer varchar(50) NOT NULL,
+	model        varchar(50) NOT NULL,
+	firmware     varchar(50) NOT NULL,
+	ownNumber    varchar(16) NOT NULL,
+	connected    datetime NOT NULL,
+	PRIMARY KEY (imei)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8`
+
+// Columns added to existing tables since the first version of smsd. Tables
+// created by older versions are upgraded by upgradeTables.
+var addColumns = []string{
+	"ALTER TABLE " + outboxTable + " ADD COLUMN flash boolean NOT NULL AFTER del",
+	"ALTER TABLE " + outboxTable + " ADD COLUMN valid int unsigned NOT NULL AFTER flash",
+	"ALTER TABLE " + recipientsTable + " ADD COLUMN msgRef tinyint unsigned NOT NULL AFTER sent",
+	"ALTER TABLE " + recipientsTable + " ADD COLUMN status tinyint unsigned NOT NULL AFTER report",
+	"ALTER TABLE " + inboxTable + " ADD COLUMN ownNumber varchar(16) NOT NULL AFTER note",
+}
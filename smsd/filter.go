@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/ziutek/gogammu"
 	"io"
 	"io/ioutil"
 	"os/exec"
@@ -27,8 +28,15 @@ func (f *Filter) Path() string {
 	return f.path
 }
 
+// filterMsg is sent to filter process: Msg fields and all metadata of
+// received SMS (read only).
+type filterMsg struct {
+	*Msg
+	SMS *gammu.SMS
+}
+
 // If error == nil bool means: accept/deny
-func (f *Filter) Filter(msg *Msg) (bool, error) {
+func (f *Filter) Filter(msg *Msg, sms *gammu.SMS) (bool, error) {
 	needRetry := true
 retry:
 	if f.cmd == nil {
@@ -54,7 +62,7 @@ retry:
 		f.out = json.NewEncoder(stdin)
 	}
 
-	err := f.out.Encode(msg)
+	err := f.out.Encode(filterMsg{msg, sms})
 	if err != nil {
 		f.cmd.Wait()
 		f.cmd = nil
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"github.com/ziutek/gogammu/pdu"
 	"github.com/ziutek/mymysql/autorc"
 	"io"
 	"log"
@@ -19,6 +20,8 @@ import (
 // NAME VALUE. Implemented parameters:
 // report        - report required
 // delete        - delete message after sending (wait for reports, if required)
+// flash         - send as flash message (class 0)
+// valid PERIOD  - validity period (eg. 30m, 2h), SMSC default if not set
 //               - empty line
 // Message body
 // .             - '.' as first and only character in line
@@ -30,13 +33,14 @@ type Input struct {
 	smsd                           *SMSd
 	db                             *autorc.Conn
 	knownSrc                       []string
+	translitSrc                    map[string]bool
 	proto, addr                    string
 	ln                             net.Listener
 	outboxInsert, recipientsInsert autorc.Stmt
 	stop                           bool
 }
 
-func NewInput(smsd *SMSd, proto, addr string, db *autorc.Conn, src []string) *Input {
+func NewInput(smsd *SMSd, proto, addr string, db *autorc.Conn, src, translit []string) *Input {
 	in := new(Input)
 	in.smsd = smsd
 	in.db = db
@@ -46,6 +50,10 @@ func NewInput(smsd *SMSd, proto, addr string, db *autorc.Conn, src []string) *In
 	in.proto = proto
 	in.addr = addr
 	in.knownSrc = src
+	in.translitSrc = make(map[string]bool)
+	for _, s := range translit {
+		in.translitSrc[s] = true
+	}
 	return in
 }
 
@@ -56,6 +64,8 @@ SET
 	src=?,
 	report=?,
 	del=?,
+	flash=?,
+	valid=?,
 	body=?
 `
 
@@ -94,7 +104,8 @@ func (in *Input) handle(c net.Conn) {
 		return
 	}
 	// Read options until first empty line
-	var del, report bool
+	var del, report, flash bool
+	var valid time.Duration
 	for {
 		l, ok := readLine(r)
 		if !ok {
@@ -103,11 +114,25 @@ func (in *Input) handle(c net.Conn) {
 		if l == "" {
 			break
 		}
-		switch l {
+		name, value := l, ""
+		if n := strings.IndexByte(l, ' '); n != -1 {
+			name, value = l[:n], strings.TrimSpace(l[n+1:])
+		}
+		switch name {
 		case "report":
 			report = true
 		case "delete":
 			del = true
+		case "flash":
+			flash = true
+		case "valid":
+			var err error
+			valid, err = time.ParseDuration(value)
+			if err != nil || valid < 0 {
+				log.Printf("Bad validity period `%s` from %s", value, from)
+				io.WriteString(c, "Bad validity period\n")
+				return
+			}
 		}
 	}
 	// Read a message body
@@ -126,8 +151,28 @@ func (in *Input) handle(c net.Conn) {
 		body = append(body, buf...)
 		prevIsPrefix = isPrefix
 	}
+	text := string(body[1:])
+	if in.translitSrc[from] {
+		var replaced []rune
+		text, replaced = pdu.Transliterate(text)
+		if len(replaced) > 0 {
+			log.Printf(
+				"Transliterated %q in message from %s", string(replaced), from,
+			)
+		}
+	}
+	// Check message length
+	seg := in.smsd.countSegments(text)
+	if seg.Parts > in.smsd.maxParts {
+		log.Printf("Message from %s too long: %d parts", from, seg.Parts)
+		io.WriteString(c, "Message too long\n")
+		return
+	}
 	// Insert message into Outbox
-	_, res, err := in.outboxInsert.Exec(time.Now(), from, report, del, body[1:])
+	_, res, err := in.outboxInsert.Exec(
+		time.Now(), from, report, del, flash, uint(valid/time.Second),
+		text,
+	)
 	if err != nil {
 		log.Printf("Can't insert message from %s into Outbox: %s", from, err)
 		// Send error response, ignore errors
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"github.com/ziutek/gogammu"
+	"github.com/ziutek/gogammu/pdu"
 	"github.com/ziutek/mymysql/autorc"
 	_ "github.com/ziutek/mymysql/native"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -32,6 +35,38 @@ func parseList(l string) []string {
 	return a
 }
 
+func boolOption(cfg map[string]string, name string) bool {
+	switch strings.ToLower(cfg[name]) {
+	case "", "no", "false", "0":
+		return false
+	case "yes", "true", "1":
+		return true
+	}
+	log.Printf("Wrong value for '%s' option: '%s'", name, cfg[name])
+	os.Exit(1)
+	return false
+}
+
+// newStateMachine creates state machine using modem settings from smsd config
+// (if Device option is set) or using gammurc file.
+func newStateMachine(cfg map[string]string) (*gammu.StateMachine, error) {
+	if dev := cfg["Device"]; dev != "" {
+		log.Println("Device:", dev)
+		return gammu.NewStateMachineFromConfig(gammu.Config{
+			Device:     dev,
+			Connection: cfg["Connection"],
+			Model:      cfg["Model"],
+			SyncTime:   boolOption(cfg, "SyncTime"),
+			LockDevice: boolOption(cfg, "LockDevice"),
+			LogFile:    cfg["GammuLog"],
+			LogFormat:  cfg["GammuLogFormat"],
+		})
+	}
+	rc, section := cfg["GammuRC"], cfg["GammuSection"]
+	log.Printf("Gammu config: '%s' section: '%s'", rc, section)
+	return gammu.NewStateMachineSection(rc, section)
+}
+
 func main() {
 	if len(os.Args) != 2 {
 		log.Printf("Usage: %s CONFIG_FILE\n", os.Args[0])
@@ -59,6 +94,10 @@ func main() {
 		os.Exit(1)
 	}
 	source := parseList(c)
+	var translit []string
+	if c, _ = cfg["Transliterate"]; c != "" {
+		translit = parseList(c)
+	}
 
 	pullInt := 17 * time.Second // if 15s my phone works bad
 	c, _ = cfg["PullInt"]
@@ -70,10 +109,59 @@ func main() {
 		}
 	}
 
+	partsHold := 10 * time.Minute
+	c, _ = cfg["PartsHold"]
+	if c != "" {
+		partsHold, err = time.ParseDuration(c)
+		if err != nil {
+			log.Printf("Wrong value for 'PartsHold' option: '%s'", c)
+			os.Exit(1)
+		}
+	}
+
+	var langs []pdu.Language
+	c, _ = cfg["Languages"]
+	if c != "" {
+		for _, name := range parseList(c) {
+			l, ok := pdu.ParseLanguage(name)
+			if !ok {
+				log.Printf("Wrong value for 'Languages' option: '%s'", name)
+				os.Exit(1)
+			}
+			if !pdu.HasLockingShift(l) && !gammu.CanSendSingleShift() {
+				log.Printf(
+					"Language '%s' in 'Languages' option is unsupported: it "+
+						"has only single shift table and this libGammu can't "+
+						"send it",
+					name,
+				)
+				os.Exit(1)
+			}
+			langs = append(langs, l)
+		}
+	}
+
+	maxParts := gammu.MaxParts
+	c, _ = cfg["MaxParts"]
+	if c != "" {
+		maxParts, err = strconv.Atoi(c)
+		if err != nil || maxParts < 1 || maxParts > gammu.MaxParts {
+			log.Printf("Wrong value for 'MaxParts' option: '%s'", c)
+			os.Exit(1)
+		}
+	}
+
 	numId, _ := cfg["NumId"]
 	filter, _ := cfg["Filter"]
+	simPin, _ := cfg["SimPin"]
+
+	sm, err := newStateMachine(cfg)
+	if err != nil {
+		log.Println("Can't create gammu state machine:", err)
+		os.Exit(1)
+	}
 
-	smsd = NewSMSd(db, numId, filter, pullInt)
+	smsd = NewSMSd(sm, db, numId, filter, simPin, pullInt, partsHold, langs, maxParts)
 
 	ins = make([]*Input, len(listen))
 	for i, a := range listen {
@@ -81,7 +169,7 @@ func main() {
 		if strings.IndexRune(a, ':') == -1 {
 			proto = "unix"
 		}
-		ins[i] = NewInput(smsd, proto, a, db.Clone(), source)
+		ins[i] = NewInput(smsd, proto, a, db.Clone(), source, translit)
 	}
 
 	smsd.Start()
@@ -1,13 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/ziutek/gogammu"
+	"github.com/ziutek/gogammu/pdu"
 	"github.com/ziutek/mymysql/autorc"
 	_ "github.com/ziutek/mymysql/native"
-	"io"
 	"log"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -15,33 +16,60 @@ type SMSd struct {
 	sm *gammu.StateMachine
 	db *autorc.Conn
 
-	end, newMsg chan event
-	wait        bool
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	done, newMsg chan event
+	incoming     chan gammu.IncomingSMS
+	wait         bool
 
 	gammuErrors, gammuConnErrors uint
 
+	simPin    string // Cleared after first attempt
+	ownNumber string // Number of SIM in modem (if known)
+	device    device
+	status    status
+	ringing   *gammu.Call
+	ringTime  time.Time // First RING
+	lastRing  time.Time
+	missed    []missedCall
+
 	sqlNumToId string
 
 	stmtOutboxGet, stmtRecipGet, stmtRecipSent, stmtInboxPut,
-	stmtRecipReport, stmtOutboxDel, stmtNumToId autorc.Stmt
+	stmtRecipReport, stmtOutboxDel, stmtNumToId, stmtCallPut,
+	stmtDevicePut autorc.Stmt
 
 	filter  *Filter
 	pullInt time.Duration
+	parts   *gammu.SMSAssembler
+	langs   []pdu.Language
+
+	maxParts int
 }
 
-func NewSMSd(db *autorc.Conn, numId, filter string, pullInt time.Duration) *SMSd {
+func NewSMSd(sm *gammu.StateMachine, db *autorc.Conn, numId, filter, simPin string, pullInt, partsHold time.Duration, langs []pdu.Language, maxParts int) *SMSd {
 	var err error
 
 	smsd := new(SMSd)
-	smsd.sm, err = gammu.NewStateMachine("")
-	if err != nil {
-		log.Println("Can't create gammu state machine:", err)
-		os.Exit(1)
-	}
+	smsd.sm = sm
+	smsd.sm.Unlock = smsd.unlockSIM
+
+	smsd.simPin = simPin
+	log.Println("SIM PIN set:", simPin != "")
 
 	smsd.pullInt = pullInt
 	log.Println("Pull interval:", pullInt)
 
+	smsd.parts = gammu.NewSMSAssembler(partsHold)
+	log.Println("Hold time for incomplete messages:", partsHold)
+
+	smsd.langs = langs
+	log.Println("National language tables:", langs)
+
+	smsd.maxParts = maxParts
+	log.Println("Maximum number of parts:", maxParts)
+
 	if filter != "" {
 		smsd.filter, err = NewFilter(filter)
 		if err != nil {
@@ -56,16 +84,31 @@ func NewSMSd(db *autorc.Conn, numId, filter string, pullInt time.Duration) *SMSd
 	smsd.db.Register(createOutbox)
 	smsd.db.Register(createRecipients)
 	smsd.db.Register(createInbox)
+	smsd.db.Register(createCalls)
+	smsd.db.Register(createDevices)
 	smsd.db.Register(setLocPrefix)
+	upgradeTables(smsd.db)
 	smsd.sqlNumToId = numId
-	smsd.end = make(chan event)
+	smsd.ctx, smsd.cancel = context.WithCancel(context.Background())
+	smsd.done = make(chan event)
 	smsd.newMsg = make(chan event, 1)
+	smsd.incoming = make(chan gammu.IncomingSMS, 32)
 	return smsd
 }
 
+// countSegments returns segments needed to send text using the same national
+// language tables as SendLongSMS.
+func (smsd *SMSd) countSegments(text string) pdu.Segments {
+	var single []pdu.Language
+	if gammu.CanSendSingleShift() {
+		single = smsd.langs
+	}
+	return pdu.CountSegmentsTables(text, smsd.langs, single)
+}
+
 // Selects messages from Outbox that have any recipient without sent flag set
 const outboxGet = `SELECT
-	o.id, o.src, o.report, o.body
+	o.id, o.src, o.report, o.flash, o.valid, o.body
 FROM
 	` + outboxTable + ` o
 WHERE
@@ -81,7 +124,7 @@ WHERE
 	!sent && msgId=?
 `
 
-const recipientsSent = "UPDATE " + recipientsTable + " SET sent=? WHERE id=?"
+const recipientsSent = "UPDATE " + recipientsTable + " SET sent=?, msgRef=? WHERE id=?"
 
 // Send messages from Outbox
 func (smsd *SMSd) sendMessages() (gammuError bool) {
@@ -101,10 +144,19 @@ func (smsd *SMSd) sendMessages() (gammuError bool) {
 	}
 	colMid := res.Map("id")
 	colReport := res.Map("report")
+	colFlash := res.Map("flash")
+	colValid := res.Map("valid")
 	colBody := res.Map("body")
 	for _, msg := range msgs {
 		mid := msg.Uint(colMid)
-		report := msg.Bool(colReport)
+		opts := gammu.SendOptions{
+			Report:    msg.Bool(colReport),
+			Validity:  time.Duration(msg.Uint(colValid)) * time.Second,
+			Languages: smsd.langs,
+		}
+		if msg.Bool(colFlash) {
+			opts.Class = gammu.FlashClass
+		}
 		body := msg.Str(colBody)
 
 		recipients, res, err := smsd.stmtRecipGet.Exec(mid)
@@ -120,8 +172,13 @@ func (smsd *SMSd) sendMessages() (gammuError bool) {
 			if !checkNumber(num) {
 				continue
 			}
-			err = smsd.sm.SendLongSMS(num, body, report)
+			parts, err := smsd.sm.SendLongSMSContext(
+				smsd.ctx, num, body, &opts,
+			)
 			if err != nil {
+				if err == smsd.ctx.Err() {
+					return
+				}
 				if _, ok := err.(gammu.EncodeError); ok {
 					log.Printf("Can't encode message to %s: %s", num, err)
 					continue
@@ -130,7 +187,12 @@ func (smsd *SMSd) sendMessages() (gammuError bool) {
 				log.Printf("Can't send message to %s: %s", num, err)
 				return true
 			}
-			_, _, err = smsd.stmtRecipSent.Exec(time.Now(), pid)
+			// Delivery report for the last part marks the whole message
+			msgRef := 0
+			if len(parts) > 0 {
+				msgRef = parts[len(parts)-1].MsgRef
+			}
+			_, _, err = smsd.stmtRecipSent.Exec(time.Now(), msgRef, pid)
 			if err != nil {
 				log.Printf(
 					"Can't mark a msg/recip #%d/#%d as sent: %s",
@@ -150,25 +212,31 @@ SET
 	number=?,
 	srcId=?,
 	body=?,
-	note=?
+	note=?,
+	ownNumber=?
 `
 
+// Sets TP-Status of recipient. report is set only for final status.
 const recipReport = `UPDATE
 	` + recipientsTable + `
 SET
-	report=?
+	report=?,
+	status=?
 WHERE
-	!report && (number=? || concat(@localPrefix, number)=?)
+	!report && msgRef=? && (number=? || concat(@localPrefix, number)=?)
 ORDER BY
 	abs(timediff(?, sent))
 LIMIT 1`
 
+// Msg is bound to inboxPut statement, so its fields need to match its
+// parameters.
 type Msg struct {
-	Time   time.Time
-	Number string
-	SrcId  uint
-	Body   string
-	Note   string
+	Time      time.Time
+	Number    string
+	SrcId     uint
+	Body      string
+	Note      string
+	OwnNumber string // Number of SIM that received message (read only)
 }
 
 func (smsd *SMSd) recvMessages() (gammuError bool) {
@@ -187,30 +255,54 @@ func (smsd *SMSd) recvMessages() (gammuError bool) {
 	var msg Msg
 	smsd.stmtInboxPut.Bind(&msg)
 
-	for {
-		sms, err := smsd.sm.GetSMS()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			smsd.gammuErrors++
-			log.Printf("Can't get message from phone: %s", err)
-			return true
+	// Messages are deleted from phone only after they are saved in database
+	err := smsd.parts.ReadContext(smsd.ctx, smsd.sm.ListSMS(0))
+	if err != nil {
+		if err == smsd.ctx.Err() {
+			return
+		}
+		smsd.gammuErrors++
+		log.Printf("Can't get message from phone: %s", err)
+		return true
+	}
+	msgs, err := smsd.parts.Messages()
+	if err != nil {
+		log.Printf("Can't join parts of messages: %s", err)
+		return
+	}
+	for _, sms := range msgs {
+		if smsd.ctx.Err() != nil {
+			break
+		}
+		if sms.Partial {
+			log.Printf(
+				"Incomplete message from %s (%d of %d parts)",
+				sms.Number, len(sms.Locations), sms.Parts,
+			)
 		}
 		if sms.Report {
 			// Find a message and sender in Outbox and mark it
-			m := strings.TrimSpace(sms.Body)
-			if strings.ToLower(m) == "delivered" {
-				_, _, err = smsd.stmtRecipReport.Exec(
-					sms.SMSCTime, sms.Number, sms.Number, sms.Time,
+			r := sms.StatusReport
+			var report time.Time
+			if r.Final() {
+				report = r.Discharge
+			}
+			if r.State() != gammu.ReportDelivered {
+				log.Printf(
+					"Message #%d to %s: %s (status 0x%02x)",
+					r.MsgRef, r.Recipient, r.State(), r.Status,
 				)
-				if err != nil {
-					log.Printf(
-						"Can't mark recipient %s as reported: %s",
-						sms.Number, err,
-					)
-					return
-				}
+			}
+			_, _, err = smsd.stmtRecipReport.Exec(
+				report, r.Status, r.MsgRef, r.Recipient, r.Recipient,
+				r.Submitted,
+			)
+			if err != nil {
+				log.Printf(
+					"Can't mark recipient %s as reported: %s",
+					sms.Number, err,
+				)
+				return
 			}
 		} else {
 			// Save a message in Inbox
@@ -218,6 +310,7 @@ func (smsd *SMSd) recvMessages() (gammuError bool) {
 			msg.Number = sms.Number
 			msg.SrcId = 0
 			msg.Body = sms.Body
+			msg.OwnNumber = smsd.ownNumber
 			//log.Printf("Odebrano: %+v", msg)
 			if smsd.stmtNumToId.Raw != nil {
 				id, _, err := smsd.stmtNumToId.ExecFirst(msg.Number)
@@ -236,22 +329,35 @@ func (smsd *SMSd) recvMessages() (gammuError bool) {
 					}
 				}
 			}
+			drop := false
 			if f := smsd.filter; f != nil {
-				accept, err := f.Filter(&msg)
+				accept, err := f.Filter(&msg, &sms)
 				if err != nil {
 					log.Printf("Filter error: %s", err)
 				} else if !accept {
 					// Drop this message
-					continue
+					drop = true
 				}
 			}
-			_, _, err = smsd.stmtInboxPut.Exec() // using msg
-			if err != nil {
-				log.Printf(
-					"Can't insert message from %s into Inbox: %s",
-					sms.Number, err,
-				)
-				return
+			if !drop {
+				_, _, err = smsd.stmtInboxPut.Exec() // using msg
+				if err != nil {
+					log.Printf(
+						"Can't insert message from %s into Inbox: %s",
+						sms.Number, err,
+					)
+					return
+				}
+			}
+		}
+		for _, l := range sms.Locations {
+			if l < 0 {
+				continue // Received directly, not stored in phone
+			}
+			if err = smsd.sm.DeleteSMS(l, 0); err != nil {
+				smsd.gammuErrors++
+				log.Printf("Can't delete message from phone: %s", err)
+				return true
 			}
 		}
 	}
@@ -294,23 +400,39 @@ func (smsd *SMSd) sendRecvDel(send bool) (end bool) {
 			log.Println("Too many connection errors - terminating")
 			os.Exit(1)
 		}
-		err := smsd.sm.Connect()
+		err := smsd.sm.ConnectContext(smsd.ctx)
 		if err != nil {
-			smsd.gammuConnErrors++
-			sleep := 60 * time.Second
-			log.Println("Can't connect:", err)
-			log.Println("Waiting", sleep)
-			select {
-			case <-smsd.end:
+			if err == smsd.ctx.Err() {
 				return true
-			case <-time.After(sleep):
 			}
-			return
+			if err != errSIMLocked {
+				// Waiting for SIM unlocked by hand doesn't terminate smsd
+				smsd.gammuConnErrors++
+			}
+			log.Println("Can't connect:", err)
+			return smsd.sleep(60 * time.Second)
 		}
 		smsd.gammuErrors = 0
 		smsd.gammuConnErrors = 0
+		smsd.status = status{signal: -1, battery: -1}
+		smsd.readOwnNumber()
+		smsd.readDevice()
+		if err = smsd.sm.SetIncomingCall(smsd.call); err != nil {
+			log.Println("Can't enable incoming call events:", err)
+		}
+		if err = smsd.sm.SetIncomingSMS(smsd.incoming); err != nil {
+			log.Println("Can't enable incoming SMS events:", err)
+		}
 	}
+	// Process events received since last call
+	smsd.sm.ReadDevice()
+	smsd.readIncoming()
+	smsd.saveCalls()
 
+	if !smsd.checkStatus() {
+		// Messages stay in Outbox until modem registers in network
+		send = false
+	}
 	if send {
 		if smsd.sendMessages() {
 			return
@@ -325,22 +447,301 @@ func (smsd *SMSd) sendRecvDel(send bool) (end bool) {
 	return
 }
 
+// sleep waits d. It returns true if smsd was stopped in the meantime.
+func (smsd *SMSd) sleep(d time.Duration) bool {
+	log.Println("Waiting", d)
+	select {
+	case <-smsd.ctx.Done():
+		return true
+	case <-time.After(d):
+	}
+	return false
+}
+
+var errSIMLocked = errors.New("SIM is locked")
+
+// unlockSIM is called by Connect before phone is used. It enters SimPin if SIM
+// requires it. PIN is entered only once: if it's wrong, smsd doesn't try again
+// so SIM isn't blocked. It returns errSIMLocked if SIM is still locked.
+func (smsd *SMSd) unlockSIM(sm *gammu.StateMachine) error {
+	sec, err := sm.SecurityStatus()
+	if err != nil {
+		// Not all phones report it
+		log.Println("Can't read security status:", err)
+		return nil
+	}
+	switch sec {
+	case gammu.SecNone:
+		return nil
+	case gammu.SecPIN:
+		if smsd.simPin == "" {
+			break
+		}
+		pin := smsd.simPin
+		smsd.simPin = ""
+		if err = sm.EnterSecurityCode(gammu.SecPIN, pin); err != nil {
+			log.Println("Can't enter SIM PIN (it won't be retried):", err)
+			return errSIMLocked
+		}
+		log.Println("SIM unlocked")
+		return nil
+	}
+	log.Printf("SIM is locked (%s required)", sec)
+	return errSIMLocked
+}
+
+// readOwnNumber reads own number from SIM. Errors aren't fatal because many
+// SIMs don't contain it.
+func (smsd *SMSd) readOwnNumber() {
+	nums, err := smsd.sm.OwnNumbers()
+	if err != nil {
+		log.Println("Can't read own number:", err)
+		return
+	}
+	if len(nums) == 0 {
+		log.Println("Own number isn't stored on SIM")
+		return
+	}
+	log.Println("Own numbers:", nums)
+	smsd.ownNumber = nums[0]
+}
+
+// device identifies modem and SIM
+type device struct {
+	Manufacturer, Model, Firmware, IMEI, IMSI string
+}
+
+const devicePut = `REPLACE
+	` + devicesTable + `
+SET
+	imei=?,
+	imsi=?,
+	manufacturer=?,
+	model=?,
+	firmware=?,
+	ownNumber=?,
+	connected=?
+`
+
+// readDevice reads identity of modem and SIM, logs it and saves it in Devices
+// table (if IMEI is known) for monitoring.
+func (smsd *SMSd) readDevice() {
+	sm := smsd.sm
+	d := &smsd.device
+	var err error
+	if d.Manufacturer, err = sm.Manufacturer(); err != nil {
+		log.Println("Can't read manufacturer:", err)
+	}
+	if d.Model, err = sm.Model(); err != nil {
+		log.Println("Can't read model:", err)
+	}
+	if d.Firmware, _, err = sm.Firmware(); err != nil {
+		log.Println("Can't read firmware version:", err)
+	}
+	if d.IMEI, err = sm.IMEI(); err != nil {
+		log.Println("Can't read IMEI:", err)
+	}
+	if d.IMSI, err = sm.SIMIMSI(); err != nil {
+		log.Println("Can't read IMSI:", err)
+	}
+	log.Printf(
+		"Device: %s %s (firmware %s) IMEI: %s, SIM IMSI: %s",
+		d.Manufacturer, d.Model, d.Firmware, d.IMEI, d.IMSI,
+	)
+	if d.IMEI == "" {
+		return
+	}
+	if !prepareOnce(smsd.db, &smsd.stmtDevicePut, devicePut) {
+		return
+	}
+	_, _, err = smsd.stmtDevicePut.Exec(
+		d.IMEI, d.IMSI, d.Manufacturer, d.Model, d.Firmware, smsd.ownNumber,
+		time.Now(),
+	)
+	if err != nil {
+		log.Printf("Can't save device %s in %s: %s", d.IMEI, devicesTable, err)
+	}
+}
+
+// status contains last sampled state of modem
+type status struct {
+	signal  int // % (-1 if unknown)
+	battery int // % (-1 if unknown or not powered from battery)
+	network gammu.NetworkState
+}
+
+const (
+	lowSignal  = 20 // %
+	lowBattery = 20 // %
+)
+
+func low(v, threshold int) bool {
+	return v >= 0 && v < threshold
+}
+
+// checkStatus samples signal, battery and network state and logs their
+// degradation and recovery. It returns false if modem isn't registered in
+// network. Modem that can't report network state is assumed registered.
+func (smsd *SMSd) checkStatus() (registered bool) {
+	sm, prev := smsd.sm, smsd.status
+	cur := status{signal: -1, battery: -1, network: gammu.NetworkUnknown}
+	if sq, err := sm.SignalQuality(); err == nil {
+		cur.signal = sq.Percent
+	}
+	if bc, err := sm.BatteryCharge(); err == nil && bc.State == gammu.BatteryPowered {
+		cur.battery = bc.Percent
+	}
+	ni, err := sm.NetworkInfo()
+	if err == nil {
+		cur.network = ni.State
+	}
+	smsd.status = cur
+
+	switch {
+	case low(cur.signal, lowSignal) && !low(prev.signal, lowSignal):
+		log.Printf("Low signal: %d%%", cur.signal)
+	case low(prev.signal, lowSignal) && cur.signal >= lowSignal:
+		log.Printf("Signal OK: %d%%", cur.signal)
+	}
+	switch {
+	case low(cur.battery, lowBattery) && !low(prev.battery, lowBattery):
+		log.Printf("Low battery: %d%%", cur.battery)
+	case low(prev.battery, lowBattery) && !low(cur.battery, lowBattery):
+		log.Println("Battery OK")
+	}
+	if err != nil {
+		if prev.network != gammu.NetworkUnknown {
+			log.Println("Can't read network state:", err)
+		}
+		return true
+	}
+	if cur.network != prev.network {
+		log.Printf(
+			"Network: %s %s (%s) LAC: %s, CID: %s, GPRS attached: %t",
+			ni.State, ni.Code, ni.Name, ni.LAC, ni.CID, ni.GPRS,
+		)
+		if !ni.State.Registered() {
+			log.Println("Not registered in network - sending suspended")
+		}
+	}
+	return ni.State.Registered()
+}
+
+const callPut = "INSERT " + callsTable + " SET time=?, number=?, ownNumber=?"
+
+// missedCall is missed call waiting for insert into database
+type missedCall struct {
+	time   time.Time
+	number string
+}
+
+// Incoming call that doesn't ring longer than ringTimeout is missed (many
+// modems don't report that caller hung up, they just stop sending RING).
+const ringTimeout = 10 * time.Second
+
+// call is called by gammu for call events. smsd doesn't answer calls, so
+// every incoming call that ends is logged as missed. It doesn't use database
+// because it's called inside of StateMachine methods: missed calls are saved
+// later by saveCalls.
+func (smsd *SMSd) call(c gammu.Call) {
+	switch {
+	case c.Status == gammu.CallIncoming:
+		if smsd.ringing == nil {
+			smsd.ringing = &c
+			smsd.ringTime = time.Now()
+			log.Println("Incoming call from", c.Number)
+		} else if smsd.ringing.Number == "" {
+			// Caller ID can be reported after first ring
+			smsd.ringing.Number = c.Number
+		}
+		smsd.lastRing = time.Now()
+	case c.Status == gammu.CallEstablished:
+		smsd.ringing = nil
+	case c.Status.Ended() && smsd.ringing != nil:
+		smsd.missCall()
+	}
+}
+
+// missCall moves ringing call to missed calls.
+func (smsd *SMSd) missCall() {
+	number := smsd.ringing.Number
+	smsd.ringing = nil
+	log.Println("Missed call from", number)
+	smsd.missed = append(smsd.missed, missedCall{smsd.ringTime, number})
+}
+
+// saveCalls expires ringing call that stopped ringing and inserts missed
+// calls into database.
+func (smsd *SMSd) saveCalls() {
+	if smsd.ringing != nil && time.Now().Sub(smsd.lastRing) > ringTimeout {
+		smsd.missCall()
+	}
+	if len(smsd.missed) == 0 {
+		return
+	}
+	if !prepareOnce(smsd.db, &smsd.stmtCallPut, callPut) {
+		return
+	}
+	for len(smsd.missed) > 0 {
+		c := smsd.missed[0]
+		_, _, err := smsd.stmtCallPut.Exec(c.time, c.number, smsd.ownNumber)
+		if err != nil {
+			log.Printf("Can't insert missed call from %s: %s", c.number, err)
+			if autorc.IsNetErr(err) {
+				return // Try again later
+			}
+		}
+		smsd.missed = smsd.missed[1:]
+	}
+}
+
+// readIncoming passes messages reported by incoming SMS events to assembler.
+// It returns true if any event was received.
+func (smsd *SMSd) readIncoming() (recv bool) {
+	for len(smsd.incoming) > 0 {
+		smsd.parts.Add(<-smsd.incoming)
+		recv = true
+	}
+	return
+}
+
+// How often smsd checks for events (eg. incoming messages) between polls
+const eventInt = time.Second
+
 func (smsd *SMSd) loop() {
+	defer close(smsd.done)
 	send := true
+	events := time.NewTicker(eventInt)
+	defer events.Stop()
 	for {
 		if smsd.sendRecvDel(send) {
 			return
 		}
 		// Wait for some event or timeout
-		select {
-		case <-smsd.end:
-			return
-		case <-smsd.newMsg:
-			send = true
-		case <-time.After(smsd.pullInt):
-			// if there is no newMsg signal, send and del two times less
-			// frequently than recv
-			send = !send
+		pull := time.After(smsd.pullInt)
+	wait:
+		for {
+			select {
+			case <-smsd.ctx.Done():
+				return
+			case <-smsd.newMsg:
+				send = true
+				break wait
+			case <-pull:
+				// if there is no newMsg signal, send and del two times less
+				// frequently than recv
+				send = !send
+				break wait
+			case <-events.C:
+				// Save incoming messages without waiting for the next poll
+				if smsd.sm.IsConnected() {
+					smsd.sm.ReadDevice()
+					if smsd.readIncoming() {
+						smsd.recvMessages()
+					}
+				}
+				smsd.saveCalls()
+			}
 		}
 	}
 }
@@ -349,8 +750,10 @@ func (smsd *SMSd) Start() {
 	go smsd.loop()
 }
 
+// Stop interrupts current phone operation and waits for the loop to end.
 func (smsd *SMSd) Stop() {
-	smsd.end <- event{}
+	smsd.cancel()
+	<-smsd.done
 }
 
 func (smsd *SMSd) NewMsg() {
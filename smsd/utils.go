@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"errors"
 	"github.com/ziutek/mymysql/autorc"
+	"github.com/ziutek/mymysql/mysql"
 	"log"
 	"os"
+	"time"
 	"unicode"
 )
 
@@ -35,6 +37,28 @@ func prepareOnce(db *autorc.Conn, stmt *autorc.Stmt, sql string) bool {
 	return false
 }
 
+// upgradeTables adds columns that are missing in tables created by older
+// versions of smsd. It waits for database if it isn't available.
+func upgradeTables(db *autorc.Conn) {
+	for _, sql := range addColumns {
+		for {
+			_, _, err := db.Query(sql)
+			if err == nil {
+				log.Printf("Upgraded table: `%s`", sql)
+				break
+			}
+			if e, ok := err.(*mysql.Error); ok && e.Code == mysql.ER_DUP_FIELDNAME {
+				break // Already upgraded
+			}
+			log.Printf("Can't upgrade table `%s`: %s", sql, err)
+			if !autorc.IsNetErr(err) {
+				os.Exit(1)
+			}
+			time.Sleep(10 * time.Second)
+		}
+	}
+}
+
 func checkNumber(num string) bool {
 	if num[0] == '+' {
 		num = num[1:]
@@ -0,0 +1,142 @@
+package gammu
+
+/*
+#include <gammu.h>
+*/
+import "C"
+
+// SignalQuality describes quality of radio signal
+type SignalQuality struct {
+	Strength     int // dBm (-1 if unknown)
+	Percent      int // 0-100 (-1 if unknown)
+	BitErrorRate int // % (-1 if unknown)
+}
+
+// SignalQuality returns current quality of radio signal.
+func (sm *StateMachine) SignalQuality() (sq SignalQuality, err error) {
+	var s C.GSM_SignalQuality
+	if e := C.GSM_GetSignalQuality(sm.g, &s); e != C.ERR_NONE {
+		err = Error{"GetSignalQuality", e}
+		return
+	}
+	sq.Strength = int(s.SignalStrength)
+	sq.Percent = int(s.SignalPercent)
+	sq.BitErrorRate = int(s.BitErrorRate)
+	return
+}
+
+// ChargeState describes power source of phone
+type ChargeState int
+
+const (
+	BatteryPowered      ChargeState = C.GSM_BatteryPowered // Powered from battery
+	BatteryConnected    ChargeState = C.GSM_BatteryConnected
+	BatteryCharging     ChargeState = C.GSM_BatteryCharging
+	BatteryNotConnected ChargeState = C.GSM_BatteryNotConnected
+	BatteryFull         ChargeState = C.GSM_BatteryFull
+	PowerFault          ChargeState = C.GSM_PowerFault
+)
+
+func (s ChargeState) String() string {
+	switch s {
+	case BatteryPowered:
+		return "battery powered"
+	case BatteryConnected:
+		return "battery connected"
+	case BatteryCharging:
+		return "charging"
+	case BatteryNotConnected:
+		return "battery not connected"
+	case BatteryFull:
+		return "battery full"
+	case PowerFault:
+		return "power fault"
+	}
+	return "unknown"
+}
+
+// BatteryCharge describes state of phone battery
+type BatteryCharge struct {
+	Percent     int // 0-100 (-1 if unknown)
+	State       ChargeState
+	Voltage     int // mV (-1 if unknown)
+	Temperature int // °C (-1 if unknown)
+}
+
+// BatteryCharge returns state of phone battery.
+func (sm *StateMachine) BatteryCharge() (bc BatteryCharge, err error) {
+	var b C.GSM_BatteryCharge
+	if e := C.GSM_GetBatteryCharge(sm.g, &b); e != C.ERR_NONE {
+		err = Error{"GetBatteryCharge", e}
+		return
+	}
+	bc.Percent = int(b.BatteryPercent)
+	bc.State = ChargeState(b.ChargeState)
+	bc.Voltage = int(b.BatteryVoltage)
+	bc.Temperature = int(b.BatteryTemperature)
+	return
+}
+
+// NetworkState describes registration in network
+type NetworkState int
+
+const (
+	NetworkHome       NetworkState = C.GSM_HomeNetwork
+	NetworkNone       NetworkState = C.GSM_NoNetwork
+	NetworkRoaming    NetworkState = C.GSM_RoamingNetwork
+	NetworkDenied     NetworkState = C.GSM_RegistrationDenied
+	NetworkUnknown    NetworkState = C.GSM_NetworkStatusUnknown
+	NetworkRequesting NetworkState = C.GSM_RequestingNetwork // Searching
+)
+
+func (s NetworkState) String() string {
+	switch s {
+	case NetworkHome:
+		return "home network"
+	case NetworkNone:
+		return "no network"
+	case NetworkRoaming:
+		return "roaming"
+	case NetworkDenied:
+		return "registration denied"
+	case NetworkUnknown:
+		return "unknown"
+	case NetworkRequesting:
+		return "searching"
+	}
+	return "unknown"
+}
+
+// Registered returns true if phone is registered in home network or roaming.
+func (s NetworkState) Registered() bool {
+	return s == NetworkHome || s == NetworkRoaming
+}
+
+// NetworkInfo describes network in which phone is registered
+type NetworkInfo struct {
+	Code        string // MCC MNC, eg. "260 02"
+	Name        string // Operator name (if provided by phone)
+	State       NetworkState
+	LAC         string // Location area code (hex)
+	CID         string // Cell ID (hex)
+	PacketState NetworkState
+	GPRS        bool // Attached to GPRS
+}
+
+// NetworkInfo returns information about network in which phone is
+// registered.
+func (sm *StateMachine) NetworkInfo() (ni NetworkInfo, err error) {
+	var n C.GSM_NetworkInfo
+	if e := C.GSM_GetNetworkInfo(sm.g, &n); e != C.ERR_NONE {
+		err = Error{"GetNetworkInfo", e}
+		return
+	}
+	ni.Code = C.GoString(&n.NetworkCode[0])
+	ni.Name = encodeUTF8(&n.NetworkName[0])
+	ni.State = NetworkState(n.State)
+	ni.LAC = C.GoString(&n.LAC[0])
+	ni.CID = C.GoString(&n.CID[0])
+	ni.PacketState = NetworkState(n.PacketState)
+	ni.GPRS = n.GPRS == C.GSM_GPRS_Attached
+	return
+}
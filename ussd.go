@@ -0,0 +1,122 @@
+package gammu
+
+/*
+#include <stdlib.h>
+#include <gammu.h>
+
+void goIncomingUSSD(GSM_StateMachine *sm, GSM_USSDMessage *ussd, void *data);
+
+static void setIncomingUSSDCallback(GSM_StateMachine *sm) {
+	GSM_SetIncomingUSSDCallback(sm, goIncomingUSSD, NULL);
+}
+*/
+import "C"
+import (
+	"context"
+	"time"
+	"unsafe"
+)
+
+// USSDStatus describes state of USSD session
+type USSDStatus int
+
+const (
+	USSDUnknown        USSDStatus = C.USSD_Unknown
+	USSDNoActionNeeded USSDStatus = C.USSD_NoActionNeeded // Session ended
+	USSDActionNeeded   USSDStatus = C.USSD_ActionNeeded   // Network waits for reply
+	USSDTerminated     USSDStatus = C.USSD_Terminated     // Terminated by network
+	USSDAnotherClient  USSDStatus = C.USSD_AnotherClient  // Handled by other client
+	USSDNotSupported   USSDStatus = C.USSD_NotSupported
+	USSDTimeout        USSDStatus = C.USSD_Timeout
+)
+
+func (s USSDStatus) String() string {
+	switch s {
+	case USSDUnknown:
+		return "unknown"
+	case USSDNoActionNeeded:
+		return "no action needed"
+	case USSDActionNeeded:
+		return "action needed"
+	case USSDTerminated:
+		return "terminated"
+	case USSDAnotherClient:
+		return "another client"
+	case USSDNotSupported:
+		return "not supported"
+	case USSDTimeout:
+		return "timeout"
+	}
+	return "unknown"
+}
+
+// USSD is USSD message received from network
+type USSD struct {
+	Status USSDStatus
+	Text   string
+}
+
+// SetIncomingUSSD enables reporting of USSD messages to f (or disables it if
+// f == nil). It should be called after every Connect. f is called from
+// inside of StateMachine methods (eg. ReadDevice), so it can't call them.
+// Responses to DialService are reported to f too.
+func (sm *StateMachine) SetIncomingUSSD(f func(USSD)) error {
+	setHandlers(sm.g, func(h *handlers) { h.ussd = f })
+	enable := C.gboolean(C.FALSE)
+	if f != nil {
+		C.setIncomingUSSDCallback(sm.g)
+		enable = C.TRUE
+	}
+	if e := C.GSM_SetIncomingUSSD(sm.g, enable); e != C.ERR_NONE {
+		return Error{"SetIncomingUSSD", e}
+	}
+	return nil
+}
+
+// DialService sends USSD code (eg. "*101#") and waits for the network
+// response. If returned Status is USSDActionNeeded the session continues:
+// call DialService again with reply (eg. selected menu item).
+func (sm *StateMachine) DialService(code string) (USSD, error) {
+	return sm.DialServiceContext(context.Background(), code)
+}
+
+// DialServiceContext works like DialService but stops waiting for response
+// when ctx is done. It returns ctx.Err() in this case.
+func (sm *StateMachine) DialServiceContext(ctx context.Context, code string) (u USSD, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	reply := make(chan USSD, 1)
+	setHandlers(sm.g, func(h *handlers) { h.ussdReply = reply })
+	defer setHandlers(sm.g, func(h *handlers) { h.ussdReply = nil })
+	C.setIncomingUSSDCallback(sm.g)
+	if e := C.GSM_SetIncomingUSSD(sm.g, C.TRUE); e != C.ERR_NONE {
+		err = Error{"SetIncomingUSSD", e}
+		return
+	}
+	if getHandlers(sm.g).ussd == nil {
+		// Restore state set by SetIncomingUSSD
+		defer C.GSM_SetIncomingUSSD(sm.g, C.FALSE)
+	}
+	cc := C.CString(code)
+	defer C.free(unsafe.Pointer(cc))
+	if e := C.GSM_DialService(sm.g, cc); e != C.ERR_NONE {
+		err = Error{"DialService", e}
+		return
+	}
+	// Wait for response (sent by goIncomingUSSD)
+	t := time.Now()
+	for time.Now().Sub(t) < sm.Timeout {
+		select {
+		case u = <-reply:
+			return
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		default:
+		}
+		C.GSM_ReadDevice(sm.g, C.TRUE)
+	}
+	err = Error{"DialService", C.ERR_TIMEOUT}
+	return
+}